@@ -8,77 +8,9 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"github.com/whales-collective/function-calling/engine"
 )
 
-func getOpenAIClient(chatURL string) openai.Client {
-	client := openai.NewClient(
-		option.WithBaseURL(chatURL),
-		option.WithAPIKey(""),
-	)
-	return client
-}
-
-type Engine struct {
-	ctx    context.Context
-	client openai.Client
-	model  string
-	tools  []openai.ChatCompletionToolParam
-}
-
-func (e *Engine) Tools(tools []openai.ChatCompletionToolParam) {
-	e.tools = tools
-}
-
-func (e *Engine) ToolCompletion(messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageToolCall, error) {
-	params := openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    e.model,
-		Tools:    e.tools,
-		// Enable parallel tool calls for DMR, no need for this with Ollama
-		ParallelToolCalls: openai.Bool(true),
-		Temperature:       openai.Opt(0.0),
-	}
-
-	completion, err := e.client.Chat.Completions.New(e.ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("error creating tool completion: %w", err)
-	}
-	return completion.Choices[0].Message.ToolCalls, nil
-
-}
-
-type EngineOption func(*Engine)
-
-func NewEngine(options ...EngineOption) *Engine {
-	engine := &Engine{}
-	// Apply all options
-	for _, option := range options {
-		option(engine)
-	}
-	return engine
-}
-
-func WithDockerModelRunner(ctx context.Context) EngineOption {
-	return func(engine *Engine) {
-		engine.ctx = ctx
-		engine.client = getOpenAIClient(os.Getenv("MODEL_RUNNER_BASE_URL"))
-	}
-}
-
-func WithOllama(ctx context.Context) EngineOption {
-	return func(engine *Engine) {
-		engine.ctx = ctx
-		engine.client = getOpenAIClient(os.Getenv("OLLAMA_BASE_URL"))
-	}
-}
-
-func WithModel(model string) EngineOption {
-	return func(engine *Engine) {
-		engine.model = model
-	}
-}
-
 func GetToolsCatalog() []openai.ChatCompletionToolParam {
 
 	vulcanSaluteTool := openai.ChatCompletionToolParam{
@@ -110,8 +42,8 @@ func main() {
 		log.Fatalln("😡", err)
 	}
 
-	dmrEngine := NewEngine(WithDockerModelRunner(ctx), WithModel(os.Getenv("MODEL_RUNNER_LLM")))
-	ollamaEngine := NewEngine(WithOllama(ctx), WithModel(os.Getenv("OLLAMA_LLM")))
+	dmrEngine := engine.NewEngine(engine.WithDockerModelRunner(ctx), engine.WithModel(os.Getenv("MODEL_RUNNER_LLM")))
+	ollamaEngine := engine.NewEngine(engine.WithOllama(ctx), engine.WithModel(os.Getenv("OLLAMA_LLM")))
 
 	dmrEngine.Tools(GetToolsCatalog())
 	ollamaEngine.Tools(GetToolsCatalog())
@@ -169,23 +101,10 @@ func main() {
 		fmt.Println("🦙", toolCall.Function.Name, toolCall.Function.Arguments)
 	}
 
-	// Check if both tool calls are equal and have the same number of items
-	if len(dmrToolCalls) != len(ollamaToolCalls) {
-		fmt.Println("😠 Tool calls do not have thhe same number of items", len(dmrToolCalls), "vs", len(ollamaToolCalls))
-		return
-	}
-
-	for i := range dmrToolCalls {
-		if dmrToolCalls[i].Function.Name != ollamaToolCalls[i].Function.Name {
-			fmt.Println("😠 Tool calls are not equal")
-			return
-		}
-		if dmrToolCalls[i].Function.Arguments != ollamaToolCalls[i].Function.Arguments {
-			fmt.Println("😠 Tool calls are not equal")
-			return
-		}
+	// Compare the tool calls whatever their order and the formatting of their arguments
+	comparison := engine.CompareToolCalls("🐳 dmr", dmrToolCalls, "🦙 ollama", ollamaToolCalls)
+	if err := comparison.Fprint(os.Stdout, os.Getenv("COMPARISON_FORMAT")); err != nil {
+		log.Fatalln("😡", err)
 	}
 
-	fmt.Println("✅ Tool calls are equal and have exactly the same number of items:", len(dmrToolCalls))
-
 }
@@ -10,4 +10,7 @@ require (
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
+	github.com/whales-collective/function-calling/engine v0.0.0
 )
+
+replace github.com/whales-collective/function-calling/engine => ../engine
@@ -0,0 +1,131 @@
+package cart
+
+import (
+	"errors"
+	"one-tool/models"
+	"testing"
+)
+
+func loadProducts(t *testing.T) []models.Product {
+	t.Helper()
+	products, err := models.LoadProducts("../products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return products
+}
+
+func TestResolveProduct(t *testing.T) {
+	products := loadProducts(t)
+	tests := []struct {
+		name      string
+		want      string
+		err       error
+		ambiguous bool
+	}{
+		{name: "ipad pro", want: "iPad Pro 12.9"},
+		{name: "IPAD PRO 12.9", want: "iPad Pro 12.9"},
+		{name: "ipda pro", want: "iPad Pro 12.9"},
+		{name: "Sapiens book", want: "Sapiens"},
+		{name: "sapiens", want: "Sapiens"},
+		{name: "macbook", want: "MacBook Air M3"},
+		{name: "macbook pro", ambiguous: true},
+		{name: "pro", ambiguous: true},
+		{name: "book", ambiguous: true},
+		{name: "lawn mower", err: ErrProductNotFound},
+		{name: "", err: ErrProductNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			match, err := ResolveProduct(products, test.name)
+			var ambiguous *AmbiguousProductError
+			switch {
+			case test.ambiguous:
+				if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) < 2 {
+					t.Fatalf("error %v, want an ambiguous product with candidates", err)
+				}
+			case test.err != nil:
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error %v, want %v", err, test.err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if match.Product.Name != test.want || products[match.Index].ID != match.Product.ID {
+					t.Errorf("match %s (index %d), want %s", match.Product.Name, match.Index, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddProduct(t *testing.T) {
+	products := loadProducts(t)
+	shoppingCart := NewCart()
+	match, err := ResolveProduct(products, "ipad pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stock := products[match.Index].Stock
+	for range 2 {
+		if err := shoppingCart.AddProduct(products, match, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(shoppingCart.Items) != 1 || shoppingCart.Items[0].Quantity != 2 || products[match.Index].Stock != stock-2 {
+		t.Errorf("cart %+v, stock %d, want 2 iPad Pro and a stock of %d", shoppingCart.Items, products[match.Index].Stock, stock-2)
+	}
+	if err := shoppingCart.AddProduct(products, match, stock); err == nil {
+		t.Errorf("no error adding more than the stock")
+	}
+	if err := shoppingCart.AddProduct(products, Match{Index: match.Index + 1, Product: match.Product}, 1); err == nil {
+		t.Errorf("no error adding a match of other products")
+	}
+}
+
+func TestRemoveAndUpdateItem(t *testing.T) {
+	products := loadProducts(t)
+	shoppingCart := NewCart()
+	for _, name := range []string{"ipad pro", "macbook air m3"} {
+		if err := shoppingCart.AddToCart(products, name, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ipad, err := ResolveProduct(products, "ipad pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stock := products[ipad.Index].Stock
+
+	match, err := shoppingCart.ResolveItem("ipad pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shoppingCart.RemoveItem(products, match, 1); err != nil {
+		t.Fatal(err)
+	}
+	if shoppingCart.Items[match.Index].Quantity != 2 || products[ipad.Index].Stock != stock+1 {
+		t.Errorf("cart %+v, stock %d, want 2 iPad Pro and a stock of %d", shoppingCart.Items, products[ipad.Index].Stock, stock+1)
+	}
+	if err := shoppingCart.UpdateItem(products, match, 5); err != nil {
+		t.Fatal(err)
+	}
+	if shoppingCart.Items[match.Index].Quantity != 5 || products[ipad.Index].Stock != stock-2 {
+		t.Errorf("cart %+v, stock %d, want 5 iPad Pro and a stock of %d", shoppingCart.Items, products[ipad.Index].Stock, stock-2)
+	}
+	if err := shoppingCart.UpdateItem(products, match, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(shoppingCart.Items) != 1 || products[ipad.Index].Stock != stock+3 {
+		t.Errorf("cart %+v, stock %d, want the MacBook Air only and a stock of %d", shoppingCart.Items, products[ipad.Index].Stock, stock+3)
+	}
+
+	// The match of the removed item is not a cart item anymore
+	if err := shoppingCart.RemoveItem(products, match, 1); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("error %v, want %v", err, ErrProductNotFound)
+	}
+	if err := shoppingCart.UpdateItem(products, match, 1); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("error %v, want %v", err, ErrProductNotFound)
+	}
+}
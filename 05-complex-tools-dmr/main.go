@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"one-tool/cart"
@@ -10,222 +9,186 @@ import (
 	"one-tool/tools"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"github.com/whales-collective/function-calling/engine"
 )
 
-func getOpenAIClient(chatURL string) openai.Client {
-	client := openai.NewClient(
-		option.WithBaseURL(chatURL),
-		option.WithAPIKey(""),
-	)
-	return client
+type SearchProductsArgs struct {
+	Query    string  `json:"query,omitempty" description:"Search query (words of the product name, description or category)"`
+	Category string  `json:"category,omitempty" description:"Product category"`
+	MinPrice float64 `json:"min_price,omitempty" description:"Minimum price" minimum:"0"`
+	MaxPrice float64 `json:"max_price,omitempty" description:"Maximum price" minimum:"0"`
+	InStock  bool    `json:"in_stock,omitempty" description:"Only the products in stock"`
+	SortBy   string  `json:"sort_by,omitempty" description:"Order of the results" enum:"relevance,price_asc,price_desc,name" default:"relevance"`
+	Offset   int     `json:"offset,omitempty" description:"Number of results to skip" minimum:"0"`
+	Cursor   string  `json:"cursor,omitempty" description:"The next_cursor of the previous results, to get the next page"`
+	Limit    int     `json:"limit,omitempty" description:"Maximum number of results to return" minimum:"1" default:"10"`
 }
 
-type Engine struct {
-	ctx    context.Context
-	client openai.Client
-	model  string
-	tools  []openai.ChatCompletionToolParam
-	Params openai.ChatCompletionNewParams
+type AddToCartArgs struct {
+	ProductName string `json:"product_name" description:"The name of the product to add"`
+	Quantity    int    `json:"quantity,omitempty" description:"Quantity to add" minimum:"1" default:"1"`
 }
 
-func (e *Engine) Tools(tools []openai.ChatCompletionToolParam) {
-	e.tools = tools
+type RemoveFromCartArgs struct {
+	ProductName string `json:"product_name" description:"The name of the product to remove"`
 }
 
-func (e *Engine) ToolCompletion(messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageToolCall, error) {
-
-	params := openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    e.model,
-		Tools:    e.tools,
-		// Enable parallel tool calls for DMR, no need for this with Ollama
-		ParallelToolCalls: openai.Bool(true),
-		Seed:              openai.Int(0),
-		Temperature:       openai.Opt(0.0),
-	}
-
-	e.Params = params
-
-	completion, err := e.client.Chat.Completions.New(e.ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("error creating tool completion: %w", err)
-	}
-	return completion.Choices[0].Message.ToolCalls, nil
-
+type UpdateQuantityArgs struct {
+	ProductName string `json:"product_name" description:"The name of the product to update"`
+	Quantity    int    `json:"quantity" description:"New quantity (use 0 to remove)" minimum:"0"`
 }
 
-func (e *Engine) ChatStreamCompletion(messages []openai.ChatCompletionMessageParamUnion, temperature float64, cbk func(content string)) {
-	params := openai.ChatCompletionNewParams{
-		Messages:    messages,
-		Model:       e.model,
-		Temperature: openai.Opt(temperature),
-	}
-
-	e.Params = params
-
-	stream := e.client.Chat.Completions.NewStreaming(e.ctx, params)
-
-	for stream.Next() {
-		chunk := stream.Current()
-		// Stream each chunk as it arrives
-		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-			//fmt.Print(chunk.Choices[0].Delta.Content)
-			cbk(chunk.Choices[0].Delta.Content)
-		}
-	}
-}
-
-type EngineOption func(*Engine)
-
-func NewEngine(options ...EngineOption) *Engine {
-	engine := &Engine{}
-	// Apply all options
-	for _, option := range options {
-		option(engine)
-	}
-	return engine
-}
-
-func WithDockerModelRunner(ctx context.Context) EngineOption {
-	return func(engine *Engine) {
-		engine.ctx = ctx
-		engine.client = getOpenAIClient(os.Getenv("MODEL_RUNNER_BASE_URL"))
-	}
-}
-
-func WithOllama(ctx context.Context) EngineOption {
-	return func(engine *Engine) {
-		engine.ctx = ctx
-		engine.client = getOpenAIClient(os.Getenv("OLLAMA_BASE_URL"))
-	}
-}
+type NoArgs struct{}
 
-func WithModel(model string) EngineOption {
-	return func(engine *Engine) {
-		engine.model = model
+func GetToolsRegistry(catalog *models.ProductCatalog, semanticIndex *tools.SemanticIndex, shoppingCart *cart.Cart) *engine.Registry {
+	products := catalog.Products
+	// Hybrid search (keywords and embeddings) when there is an embedding model
+	searchMode := tools.SearchModeKeyword
+	if semanticIndex != nil {
+		searchMode = tools.SearchModeHybrid
 	}
-}
 
-func GetToolsCatalog() []openai.ChatCompletionToolParam {
-
-	searchProducts := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "search_products",
-			Description: openai.String("Search for products by query, category, or price range"),
-			Parameters: openai.FunctionParameters{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Search query for product name or description",
-					},
-					"category": map[string]interface{}{
-						"type":        "string",
-						"description": "Product category (electronics, clothing, books, home, sports, beauty, toys, food)",
-					},
-					"limit": map[string]interface{}{
-						"type":        "integer",
-						"description": "Maximum number of results to return (default: 10)",
-					},
-				},
-			},
+	searchProducts := engine.NewTool("search_products", "Search for products by query (name, description or category, the most relevant first), category, price range or stock, with sorting and pagination",
+		func(args SearchProductsArgs) (string, error) {
+			result, err := tools.Search(products, tools.SearchOptions{
+				Query:    args.Query,
+				Index:    catalog.Index,
+				Mode:     searchMode,
+				Semantic: semanticIndex,
+				Category: args.Category,
+				MinPrice: args.MinPrice,
+				MaxPrice: args.MaxPrice,
+				InStock:  args.InStock,
+				SortBy:   args.SortBy,
+				Offset:   args.Offset,
+				Cursor:   args.Cursor,
+				Limit:    args.Limit,
+			})
+			if err != nil {
+				return "", fmt.Errorf("error searching products: %w", err)
+			}
+			if len(result.Products) == 0 {
+				return "", fmt.Errorf("no products found for query: %s category: %s", args.Query, args.Category)
+			}
+			fmt.Println("✅ Found", result.Total, "products:")
+			content := fmt.Sprintf("Found %d products for query '%s' in category '%s' (results %d to %d):",
+				result.Total, args.Query, args.Category, result.Offset+1, result.Offset+len(result.Products))
+			for _, product := range result.Products {
+				fmt.Printf("  - %s (%s): $%.2f\n", product.Name, product.Category, product.Price)
+				content += fmt.Sprintf("\n  - %s (%s): $%.2f, %d in stock", product.Name, product.Category, product.Price, product.Stock)
+			}
+			if result.NextCursor != "" {
+				content += fmt.Sprintf("\nnext_cursor: %s", result.NextCursor)
+			}
+			return content, nil
 		},
-	}
+		// The categories of the catalog, and their aliases ("tech" is "electronics")
+		engine.WithEnum("category", catalog.Taxonomy.Values()...),
+		engine.WithAliases("category", catalog.Taxonomy.Aliases()),
+	)
 
-	addToCart := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "add_to_cart",
-			Description: openai.String("Add a quantity of a product to the shopping cart"),
-			Parameters: openai.FunctionParameters{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"product_name": map[string]interface{}{
-						"type":        "string",
-						"description": "The name of the product to add",
-					},
-					"quantity": map[string]interface{}{
-						"type":        "integer",
-						"description": "Quantity to add (default: 1)",
-					},
-				},
-				"required": []string{"product_name"},
-			},
+	addToCart := engine.NewTool("add_to_cart", "Add a quantity of a product to the shopping cart",
+		func(args AddToCartArgs) (string, error) {
+			if args.Quantity <= 0 {
+				return "", fmt.Errorf("invalid quantity for adding to cart: %d", args.Quantity)
+			}
+			// "ipad pro" is "iPad Pro 12.9", an ambiguous name returns the candidates to the model
+			match, err := cart.ResolveProduct(products, args.ProductName)
+			if err != nil {
+				return "", fmt.Errorf("error adding to cart: %w", err)
+			}
+			err = shoppingCart.AddProduct(products, match, args.Quantity)
+			if err != nil {
+				return "", fmt.Errorf("error adding to cart: %w", err)
+			}
+			fmt.Printf("✅ Added %d of '%s' to the cart\n", args.Quantity, match.Product.Name)
+			return fmt.Sprintf("Added %d of '%s' to the cart", args.Quantity, match.Product.Name), nil
 		},
-	}
+	)
 
-	removeFromCart := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "remove_from_cart",
-			Description: openai.String("Remove a product from the shopping cart"),
-			Parameters: openai.FunctionParameters{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"product_name": map[string]interface{}{
-						"type":        "string",
-						"description": "The name of the product to remove",
-					},
-				},
-				"required": []string{"product_name"},
-			},
+	removeFromCart := engine.NewTool("remove_from_cart", "Remove a product from the shopping cart",
+		func(args RemoveFromCartArgs) (string, error) {
+			if args.ProductName == "" {
+				return "", fmt.Errorf("invalid product name for removal")
+			}
+			// Resolved once, the match is the cart item to remove
+			match, err := shoppingCart.ResolveItem(args.ProductName)
+			if err != nil {
+				return "", fmt.Errorf("error removing from cart: %w", err)
+			}
+			err = shoppingCart.RemoveItem(products, match, 1) // Default to removing 1 item
+			if err != nil {
+				return "", fmt.Errorf("error removing from cart: %w", err)
+			}
+			fmt.Printf("✅ Removed '%s' from the cart\n", match.Product.Name)
+			return fmt.Sprintf("Removed '%s' from the cart", match.Product.Name), nil
 		},
-	}
+	)
 
-	viewCart := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "view_cart",
-			Description: openai.String("View the current shopping cart contents and totals"),
-			Parameters: openai.FunctionParameters{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
+	viewCart := engine.NewTool("view_cart", "View the current shopping cart contents and totals",
+		func(args NoArgs) (string, error) {
+			fmt.Println("🛒 Viewing cart contents:")
+			shoppingCart.DisplayCart()
+			return shoppingCart.PrintCart(), nil
 		},
-	}
+	)
 
-	updateQuantity := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "update_quantity",
-			Description: openai.String("Update the quantity of a product in the cart"),
-			Parameters: openai.FunctionParameters{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"product_name": map[string]interface{}{
-						"type":        "string",
-						"description": "The name of the product to update",
-					},
-					"quantity": map[string]interface{}{
-						"type":        "integer",
-						"description": "New quantity (use 0 to remove)",
-					},
-				},
-				"required": []string{"product_name", "quantity"},
-			},
+	updateQuantity := engine.NewTool("update_quantity", "Update the quantity of a product in the cart",
+		func(args UpdateQuantityArgs) (string, error) {
+			if args.Quantity < 0 {
+				return "", fmt.Errorf("invalid quantity for updating: %d", args.Quantity)
+			}
+			match, err := shoppingCart.ResolveItem(args.ProductName)
+			if err != nil {
+				return "", fmt.Errorf("error updating quantity: %w", err)
+			}
+			err = shoppingCart.UpdateItem(products, match, args.Quantity)
+			if err != nil {
+				return "", fmt.Errorf("error updating quantity: %w", err)
+			}
+			fmt.Printf("✅ Updated '%s' quantity to %d\n", match.Product.Name, args.Quantity)
+			return fmt.Sprintf("Updated '%s' quantity to %d", match.Product.Name, args.Quantity), nil
 		},
-	}
+	)
 
-	checkOut := openai.ChatCompletionToolParam{
-		Function: openai.FunctionDefinitionParam{
-			Name:        "checkout",
-			Description: openai.String("Process checkout for the current cart"),
-			Parameters: openai.FunctionParameters{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
+	checkOut := engine.NewTool("checkout", "Process checkout for the current cart",
+		func(args NoArgs) (string, error) {
+			fmt.Println("✅ Checkout completed successfully!")
+			return "Checkout completed successfully!", nil
 		},
-	}
+	)
 
-	tools := []openai.ChatCompletionToolParam{
+	return engine.NewRegistry(
 		searchProducts,
 		addToCart,
 		removeFromCart,
 		viewCart,
 		updateQuantity,
 		checkOut,
+	)
+}
+
+// DisplayPullProgress displays the progress of a model pull (if the model is not available)
+func DisplayPullProgress(progress engine.PullProgress) {
+	if progress.Total > 0 {
+		fmt.Printf("⏳ %s: %s %.0f%%\n", progress.Model, progress.Status, progress.Percent())
+		return
+	}
+	fmt.Printf("⏳ %s: %s\n", progress.Model, progress.Status)
+}
+
+// RetryPolicy retries the completions while the model is loading (503) and logs each retry
+func RetryPolicy() engine.RetryPolicy {
+	policy := engine.DefaultRetryPolicy()
+	policy.RequestTimeout = 2 * time.Minute
+	policy.OnRetry = func(retry engine.Retry) {
+		fmt.Printf("🔁 attempt %d failed: %v (retrying in %s)\n", retry.Attempt, retry.Err, retry.Delay.Round(time.Millisecond))
 	}
-	return tools
+	return policy
 }
 
 func main() {
@@ -236,21 +199,33 @@ func main() {
 		// use the env variables from compose file if not found
 	}
 
-	products, err := models.LoadProducts("products.json")
+	catalog, err := models.LoadCatalog("products.json")
 	if err != nil {
 		log.Fatalln("😡", err)
 	}
+	fmt.Println("🗂️  Categories:", catalog.Taxonomy)
+	// Embeddings of the products for the semantic search (optional)
+	var semanticIndex *tools.SemanticIndex
+	if embeddingModel := os.Getenv("MODEL_RUNNER_EMBEDDING_LLM"); embeddingModel != "" {
+		llmEmbeddingEngine := engine.NewEngine(engine.WithDockerModelRunner(ctx), engine.WithModel(embeddingModel), engine.WithModelPull(DisplayPullProgress), engine.WithRetryPolicy(RetryPolicy()))
+		semanticIndex, err = tools.NewSemanticIndex(catalog.Products, llmEmbeddingEngine, "embeddings.json")
+		if err != nil {
+			log.Fatalln("😡", err)
+		}
+		fmt.Println("🧭 Hybrid search with the embeddings of", llmEmbeddingEngine)
+	}
 	// Create a new cart
-	cart := cart.NewCart()
+	shoppingCart := cart.NewCart()
+	toolsRegistry := GetToolsRegistry(catalog, semanticIndex, shoppingCart)
 
-	llmToolEngine := NewEngine(WithDockerModelRunner(ctx), WithModel(os.Getenv("MODEL_RUNNER_TOOL_LLM")))
-	llmChatEngine := NewEngine(WithDockerModelRunner(ctx), WithModel(os.Getenv("MODEL_RUNNER_CHAT_LLM")))
+	llmToolEngine := engine.NewEngine(engine.WithDockerModelRunner(ctx), engine.WithModel(os.Getenv("MODEL_RUNNER_TOOL_LLM")), engine.WithModelPull(DisplayPullProgress), engine.WithRetryPolicy(RetryPolicy()))
+	llmChatEngine := engine.NewEngine(engine.WithDockerModelRunner(ctx), engine.WithModel(os.Getenv("MODEL_RUNNER_CHAT_LLM")), engine.WithModelPull(DisplayPullProgress), engine.WithRetryPolicy(RetryPolicy()))
 
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("🛠️  Tools completion...")
 	fmt.Println(strings.Repeat("=", 50))
 
-	llmToolEngine.Tools(GetToolsCatalog())
+	llmToolEngine.Tools(toolsRegistry.Tools())
 
 	userQuestion := openai.UserMessage(`
 		search the Dune book in books 
@@ -266,10 +241,12 @@ func main() {
 	`)
 
 	// No Sysystem message
+	// Seed 0, as the tool completions of this experiment always had
 	dmrToolCalls, err := llmToolEngine.ToolCompletion(
 		[]openai.ChatCompletionMessageParamUnion{
 			userQuestion,
 		},
+		engine.WithSeed(0),
 	)
 	if err != nil {
 		log.Fatalln("😡", err)
@@ -282,132 +259,20 @@ func main() {
 		return
 	}
 
+	// Append the assistant message with the tool calls before the tool messages
+	llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, engine.ToolCallsMessage(dmrToolCalls))
+
 	// Display the tool calls
 	for idx, toolCall := range dmrToolCalls {
 		fmt.Println(idx, ".", "🐳", toolCall.Function.Name, toolCall.Function.Arguments)
 
-		switch toolCall.Function.Name {
-		case "search_products":
-			var args struct {
-				Query    string `json:"query"`
-				Category string `json:"category"`
-				Limit    int    `json:"limit"`
-			}
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-			if err != nil {
-				log.Fatalln("😡 Error unmarshalling search_products arguments:", err)
-			}
-			results := tools.SearchProducts(products, args.Query, args.Category, args.Limit)
-			if len(results) == 0 {
-				fmt.Println("😠 No products found for query:", args.Query, "category:", args.Category)
-			} else {
-				fmt.Println("✅ Found", len(results), "products:")
-				content := fmt.Sprintf("Found %d products for query '%s' in category '%s':", len(results), args.Query, args.Category)
-				for _, product := range results {
-					fmt.Printf("  - %s (%s): $%.2f\n", product.Name, product.Category, product.Price)
-					content += fmt.Sprintf("\n  - %s (%s): $%.2f", product.Name, product.Category, product.Price)
-				}
-				// Append the content to the messages
-				llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-					content, toolCall.ID,
-				))
-			}
-
-		case "add_to_cart":
-			var args struct {
-				ProductName string `json:"product_name"`
-				Quantity    int    `json:"quantity"`
-			}
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-			if err != nil {
-				log.Fatalln("😡 Error unmarshalling add_to_cart arguments:", err)
-			}
-			if args.Quantity <= 0 {
-				fmt.Println("😠 Invalid quantity for adding to cart:", args.Quantity)
-			} else {
-				err := cart.AddToCart(products, args.ProductName, args.Quantity)
-				if err != nil {
-					fmt.Println("😠 Error adding to cart:", err)
-				} else {
-					fmt.Printf("✅ Added %d of '%s' to the cart\n", args.Quantity, args.ProductName)
-					content := fmt.Sprintf("Added %d of '%s' to the cart", args.Quantity, args.ProductName)
-					// Append the content to the messages
-					llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-						content, toolCall.ID,
-					))
-				}
-			}
-		case "remove_from_cart":
-			var args struct {
-				ProductName string `json:"product_name"`
-			}
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-			if err != nil {
-				log.Fatalln("😡 Error unmarshalling remove_from_cart arguments:", err)
-			}
-			if args.ProductName == "" {
-				fmt.Println("😠 Invalid product name for removal")
-			} else {
-				err := cart.RemoveFromCart(products, args.ProductName, 1) // Default to removing 1 item
-				if err != nil {
-					fmt.Println("😠 Error removing from cart:", err)
-				} else {
-					fmt.Printf("✅ Removed '%s' from the cart\n", args.ProductName)
-					content := fmt.Sprintf("Removed '%s' from the cart", args.ProductName)
-					// Append the content to the messages
-					llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-						content, toolCall.ID,
-					))
-				}
-			}
-
-		case "view_cart":
-			fmt.Println("🛒 Viewing cart contents:")
-			cart.DisplayCart()
-			// Append the cart contents to the messages
-			llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-				cart.PrintCart(), toolCall.ID,
-			))
-
-		case "update_quantity":
-			var args struct {
-				ProductName string `json:"product_name"`
-				Quantity    int    `json:"quantity"`
-			}
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-			if err != nil {
-				log.Fatalln("😡 Error unmarshalling update_quantity arguments:", err)
-			}
-			if args.Quantity < 0 {
-				fmt.Println("😠 Invalid quantity for updating:", args.Quantity)
-			} else {
-				err := cart.UpdateCartQuantity(products, args.ProductName, args.Quantity)
-				if err != nil {
-					fmt.Println("😠 Error updating quantity:", err)
-				} else {
-					fmt.Printf("✅ Updated '%s' quantity to %d\n", args.ProductName, args.Quantity)
-					content := fmt.Sprintf("Updated '%s' quantity to %d", args.ProductName, args.Quantity)
-					// Append the content to the messages
-					llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-						content, toolCall.ID,
-					))
-				}
-			}
-		case "checkout":
-			fmt.Println("✅ Checkout completed successfully!")
-			content := "Checkout completed successfully!"
-			// Append the content to the messages
-			llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-				content, toolCall.ID,
-			))
-		default:
-			fmt.Println("😠 Unknown tool call:", toolCall.Function.Name)
-			content := fmt.Sprintf("Unknown tool call: %s", toolCall.Function.Name)
-			// Append the content to the messages
-			llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, openai.ToolMessage(
-				content, toolCall.ID,
-			))
+		// Every tool call is answered: with the content of the tool or with the error
+		toolMessage, err := toolsRegistry.ToolMessage(toolCall)
+		if err != nil {
+			fmt.Println("😠", err)
 		}
+		// Append the tool message to the messages
+		llmToolEngine.Params.Messages = append(llmToolEngine.Params.Messages, toolMessage)
 	} // End of tool calls loop
 
 	messages := []openai.ChatCompletionMessageParamUnion{
@@ -434,9 +299,12 @@ func main() {
 	fmt.Println("🤖  Chat completion...")
 	fmt.Println(strings.Repeat("=", 50))
 
-	llmChatEngine.ChatStreamCompletion(messages, 0.9, func(content string) {
+	_, err = llmChatEngine.ChatStreamCompletion(messages, 0.9, func(content string) {
 		fmt.Print(content)
 	})
+	if err != nil {
+		log.Fatalln("😡", err)
+	}
 	fmt.Println("\n" + strings.Repeat("=", 50))
 
 }
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Weights of the fields of a product in the index: a word of the name counts more
+var fieldWeights = struct{ name, description, category float64 }{name: 3, description: 1, category: 1}
+
+// Index is an in-memory inverted index of the products, with a BM25 scoring
+// over their name, description and category
+type Index struct {
+	// postings are the weighted frequencies of each term in the products (by position)
+	postings map[string]map[int]float64
+	// lengths are the weighted number of terms of each product
+	lengths []float64
+	// averageLength is the average weighted number of terms of the products
+	averageLength float64
+	// terms are the indexed terms, sorted, to expand the prefixes of the query
+	terms []string
+}
+
+// Hit is a product matching a search of the index
+type Hit struct {
+	// Position is the position of the product in the indexed products
+	Position int
+	Score    float64
+}
+
+// NewIndex indexes the products (the positions of the hits are their positions in this slice)
+func NewIndex(products []Product) *Index {
+	index := &Index{
+		postings: map[string]map[int]float64{},
+		lengths:  make([]float64, len(products)),
+	}
+	total := 0.0
+	for position, product := range products {
+		for _, field := range []struct {
+			text   string
+			weight float64
+		}{
+			{product.Name, fieldWeights.name},
+			{product.Description, fieldWeights.description},
+			{product.Category, fieldWeights.category},
+		} {
+			for _, term := range Terms(field.text) {
+				if index.postings[term] == nil {
+					index.postings[term] = map[int]float64{}
+				}
+				index.postings[term][position] += field.weight
+				index.lengths[position] += field.weight
+			}
+		}
+		total += index.lengths[position]
+	}
+	if len(products) > 0 {
+		index.averageLength = total / float64(len(products))
+	}
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+	return index
+}
+
+// Search returns the products matching at least one term of the query, the most relevant first.
+// A term of the query which is not indexed matches the indexed terms starting with it,
+// so "mac" finds the MacBooks (a product scores its best expansion of the term)
+func (i *Index) Search(query string) []Hit {
+	// Without indexed terms (e.g. an empty catalog), there is no average length to normalize the scores
+	if i.averageLength == 0 {
+		return []Hit{}
+	}
+	scores := map[int]float64{}
+	count := float64(len(i.lengths))
+	for _, term := range Terms(query) {
+		termScores := map[int]float64{}
+		for _, indexed := range i.expand(term) {
+			postings := i.postings[indexed]
+			frequency := float64(len(postings))
+			idf := math.Log(1 + (count-frequency+0.5)/(frequency+0.5))
+			for position, tf := range postings {
+				norm := bm25K1 * (1 - bm25B + bm25B*i.lengths[position]/i.averageLength)
+				termScores[position] = max(termScores[position], idf*tf*(bm25K1+1)/(tf+norm))
+			}
+		}
+		for position, score := range termScores {
+			scores[position] += score
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for position, score := range scores {
+		hits = append(hits, Hit{Position: position, Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].Position < hits[b].Position
+	})
+	return hits
+}
+
+// minPrefixLength is the minimum length of a query term matching the indexed terms by prefix
+const minPrefixLength = 2
+
+// expand returns the indexed terms matching a term of the query:
+// the term itself when it is indexed, otherwise the indexed terms starting with it
+func (i *Index) expand(term string) []string {
+	if len(i.postings[term]) > 0 {
+		return []string{term}
+	}
+	if len([]rune(term)) < minPrefixLength {
+		return nil
+	}
+	var terms []string
+	for j := sort.SearchStrings(i.terms, term); j < len(i.terms) && strings.HasPrefix(i.terms[j], term); j++ {
+		terms = append(terms, i.terms[j])
+	}
+	return terms
+}
+
+// stopWords are the words ignored by the index
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		a about above after all also an and any are as at be because been but by can
+		could do does for from get give has have i if in into is it its just like
+		me my need of on or our please show some something that the their them then
+		there these this those to up want was we what which while who with would you your
+		find search look looking`) {
+		stopWords[word] = true
+	}
+}
+
+// Terms returns the indexed terms of a text: the lowercase words, without the stop words, stemmed
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSuffix(strings.Trim(word, "'"), "'s")
+		if word == "" || stopWords[word] {
+			continue
+		}
+		terms = append(terms, Stem(word))
+	}
+	return terms
+}
+
+// Stem removes the common English suffixes of a word (plurals, -ing, -ed, -ly),
+// so "books" and "book", or "running" and "run", are the same term
+func Stem(word string) string {
+	if len([]rune(word)) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		return undouble(strings.TrimSuffix(word, "ing"))
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		return undouble(strings.TrimSuffix(word, "ed"))
+	case strings.HasSuffix(word, "ly") && len(word) > 4:
+		return strings.TrimSuffix(word, "ly")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// undouble removes the doubled last consonant of a stem ("runn" is "run")
+func undouble(stem string) string {
+	n := len(stem)
+	if n >= 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouls", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+	return stem
+}
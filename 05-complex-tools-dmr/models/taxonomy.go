@@ -0,0 +1,152 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// SubcategorySeparator separates a category and a sub-category in a category path, e.g. "electronics/phones"
+const SubcategorySeparator = "/"
+
+// CategoryAliases are the usual names of the categories, e.g. "tech" for electronics,
+// and their singular forms (only the aliases of the categories of the catalog are used)
+var CategoryAliases = map[string]string{
+	"book":       "books",
+	"sport":      "sports",
+	"toy":        "toys",
+	"electronic": "electronics",
+	"tech":       "electronics",
+	"technology": "electronics",
+	"gadgets":    "electronics",
+	"devices":    "electronics",
+	"clothes":    "clothing",
+	"apparel":    "clothing",
+	"fashion":    "clothing",
+	"wear":       "clothing",
+	"reading":    "books",
+	"novels":     "books",
+	"literature": "books",
+	"house":      "home",
+	"household":  "home",
+	"kitchen":    "home",
+	"furniture":  "home",
+	"fitness":    "sports",
+	"outdoor":    "sports",
+	"cosmetics":  "beauty",
+	"makeup":     "beauty",
+	"games":      "toys",
+	"groceries":  "food",
+	"grocery":    "food",
+}
+
+// Category is a category of the catalog with its sub-categories
+type Category struct {
+	Name          string
+	Subcategories []string
+}
+
+// Taxonomy is the categories (and sub-categories) of the products of a catalog
+type Taxonomy struct {
+	Categories []Category
+}
+
+// NewTaxonomy returns the categories and the sub-categories of the products, sorted by name
+// (lowercase, as the categories of the products)
+func NewTaxonomy(products []Product) *Taxonomy {
+	subcategories := map[string]map[string]bool{}
+	for _, product := range products {
+		category := strings.ToLower(strings.TrimSpace(product.Category))
+		if category == "" {
+			continue
+		}
+		if subcategories[category] == nil {
+			subcategories[category] = map[string]bool{}
+		}
+		if subcategory := strings.ToLower(strings.TrimSpace(product.Subcategory)); subcategory != "" {
+			subcategories[category][subcategory] = true
+		}
+	}
+
+	taxonomy := &Taxonomy{}
+	for name, names := range subcategories {
+		category := Category{Name: name}
+		for subcategory := range names {
+			category.Subcategories = append(category.Subcategories, subcategory)
+		}
+		sort.Strings(category.Subcategories)
+		taxonomy.Categories = append(taxonomy.Categories, category)
+	}
+	sort.Slice(taxonomy.Categories, func(i, j int) bool {
+		return taxonomy.Categories[i].Name < taxonomy.Categories[j].Name
+	})
+	return taxonomy
+}
+
+// Values returns the categories and the category paths of the sub-categories ("electronics/phones"),
+// the allowed values of a category argument
+func (t *Taxonomy) Values() []string {
+	var values []string
+	for _, category := range t.Categories {
+		values = append(values, category.Name)
+		for _, subcategory := range category.Subcategories {
+			values = append(values, category.Name+SubcategorySeparator+subcategory)
+		}
+	}
+	return values
+}
+
+// Aliases returns the aliases of the values of the taxonomy: the CategoryAliases of its categories
+// ("tech" for "electronics", "book" for "books"), and the sub-categories
+// without their category ("phones" for "electronics/phones") when they are in a single category
+func (t *Taxonomy) Aliases() map[string]string {
+	aliases := map[string]string{}
+	values := map[string]bool{}
+	for _, value := range t.Values() {
+		values[value] = true
+	}
+
+	// The sub-categories in several categories are ambiguous
+	subcategories := map[string][]string{}
+	for _, category := range t.Categories {
+		for _, subcategory := range category.Subcategories {
+			subcategories[subcategory] = append(subcategories[subcategory], category.Name+SubcategorySeparator+subcategory)
+		}
+	}
+	for subcategory, paths := range subcategories {
+		if len(paths) == 1 && !values[subcategory] {
+			aliases[subcategory] = paths[0]
+		}
+	}
+	for alias, category := range CategoryAliases {
+		if values[category] && !values[alias] {
+			aliases[alias] = category
+		}
+	}
+	return aliases
+}
+
+// Resolve returns the value of the taxonomy of a category name, a category path or an alias
+// (case-insensitive), false if the name is unknown
+func (t *Taxonomy) Resolve(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, value := range t.Values() {
+		if value == name {
+			return value, true
+		}
+	}
+	value, ok := t.Aliases()[name]
+	return value, ok
+}
+
+// String returns the categories of the taxonomy, e.g. "books, clothing, electronics (phones, laptops)"
+func (t *Taxonomy) String() string {
+	categories := make([]string, 0, len(t.Categories))
+	for _, category := range t.Categories {
+		if len(category.Subcategories) > 0 {
+			categories = append(categories, category.Name+" ("+strings.Join(category.Subcategories, ", ")+")")
+			continue
+		}
+		categories = append(categories, category.Name)
+	}
+	return strings.Join(categories, ", ")
+}
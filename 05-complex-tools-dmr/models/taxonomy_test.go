@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func testTaxonomy() *Taxonomy {
+	return NewTaxonomy([]Product{
+		{ID: "p0", Category: "Books"},
+		{ID: "p1", Category: "clothing"},
+		{ID: "p2", Category: "electronics", Subcategory: "Phones"},
+		{ID: "p3", Category: "electronics", Subcategory: "accessories"},
+		{ID: "p4", Category: "home", Subcategory: "accessories"},
+		{ID: "p5", Category: "sports"},
+	})
+}
+
+func TestTaxonomyValues(t *testing.T) {
+	taxonomy := testTaxonomy()
+	want := "books, clothing, electronics, electronics/accessories, electronics/phones, home, home/accessories, sports"
+	if got := strings.Join(taxonomy.Values(), ", "); got != want {
+		t.Errorf("values %s, want %s", got, want)
+	}
+	want = "books, clothing, electronics (accessories, phones), home (accessories), sports"
+	if got := taxonomy.String(); got != want {
+		t.Errorf("string %s, want %s", got, want)
+	}
+}
+
+func TestTaxonomyResolve(t *testing.T) {
+	taxonomy := testTaxonomy()
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{name: "books", want: "books", ok: true},
+		{name: " Electronics/Phones ", want: "electronics/phones", ok: true},
+		{name: "Tech", want: "electronics", ok: true},
+		{name: "BOOK", want: "books", ok: true},
+		{name: "sport", want: "sports", ok: true},
+		{name: "clothes", want: "clothing", ok: true},
+		{name: "kitchen", want: "home", ok: true},
+		// A sub-category in a single category is an alias of its path
+		{name: "phones", want: "electronics/phones", ok: true},
+		// A sub-category in several categories is ambiguous
+		{name: "accessories"},
+		// No junk plurals or singulars
+		{name: "clothings"},
+		{name: "homes"},
+		{name: "hom"},
+		// The aliases of the categories which are not in the catalog
+		{name: "toy"},
+		{name: "makeup"},
+		{name: "garden"},
+		{name: ""},
+	}
+	for _, test := range tests {
+		got, ok := taxonomy.Resolve(test.name)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestTaxonomyAliases(t *testing.T) {
+	aliases := testTaxonomy().Aliases()
+	values := map[string]bool{}
+	for _, value := range testTaxonomy().Values() {
+		values[value] = true
+	}
+	for alias, value := range aliases {
+		if !values[value] {
+			t.Errorf("alias %s of %s, which is not a value of the taxonomy", alias, value)
+		}
+		if values[alias] {
+			t.Errorf("alias %s is a value of the taxonomy", alias)
+		}
+	}
+}
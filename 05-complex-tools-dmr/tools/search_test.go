@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"encoding/base64"
+	"errors"
+	"one-tool/models"
+	"strings"
+	"testing"
+)
+
+// testProducts is the catalog of the tests of the search
+func testProducts() []models.Product {
+	return []models.Product{
+		{ID: "p0", Name: "Running Shoes", Description: "Lightweight shoes for road running", Category: "sports", Price: 90, Stock: 5},
+		{ID: "p1", Name: "Trail Backpack", Description: "A backpack for running and hiking trips", Category: "sports", Price: 60, Stock: 0},
+		{ID: "p2", Name: "Coffee Maker", Description: "Brews coffee for the whole family", Category: "home", Price: 120, Stock: 3},
+		{ID: "p3", Name: "Coffee Beans", Description: "Arabica beans from Colombia", Category: "food", Price: 15, Stock: 40},
+		{ID: "p4", Name: "Dune", Description: "Science fiction novel on a desert planet", Category: "books", Price: 10, Stock: 7},
+		{ID: "p5", Name: "Espresso Cups", Description: "Set of cups for espresso and coffee", Category: "home", Price: 25, Stock: 12},
+	}
+}
+
+// productIDs returns the IDs of products, separated by spaces
+func productIDs(products []models.Product) string {
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestSearch(t *testing.T) {
+	products := testProducts()
+	tests := []struct {
+		name    string
+		options SearchOptions
+		want    string
+		total   int
+	}{
+		{name: "no criteria", options: SearchOptions{}, want: "p0 p1 p2 p3 p4 p5", total: 6},
+		{name: "category", options: SearchOptions{Category: "HOME"}, want: "p2 p5", total: 2},
+		{name: "query and category", options: SearchOptions{Query: "coffee", Category: "home"}, want: "p2 p5", total: 2},
+		{name: "price range", options: SearchOptions{MinPrice: 20, MaxPrice: 100}, want: "p0 p1 p5", total: 3},
+		{name: "in stock", options: SearchOptions{Category: "sports", InStock: true}, want: "p0", total: 1},
+		{name: "price ascending", options: SearchOptions{Query: "coffee", SortBy: SortByPriceAsc}, want: "p3 p5 p2", total: 3},
+		{name: "price descending", options: SearchOptions{Category: "sports", SortBy: SortByPriceDesc}, want: "p0 p1", total: 2},
+		{name: "name", options: SearchOptions{Query: "coffee", SortBy: SortByName}, want: "p3 p2 p5", total: 3},
+		{name: "limit", options: SearchOptions{Limit: 2}, want: "p0 p1", total: 6},
+		{name: "offset", options: SearchOptions{Offset: 4, Limit: 5}, want: "p4 p5", total: 6},
+		{name: "offset after the results", options: SearchOptions{Offset: 10}, want: "", total: 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Search(products, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := productIDs(result.Products); got != test.want || result.Total != test.total {
+				t.Errorf("results %q (total %d), want %q (total %d)", got, result.Total, test.want, test.total)
+			}
+		})
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	products := testProducts()
+	var pages []string
+	options := SearchOptions{Limit: 4}
+	for {
+		result, err := Search(products, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, productIDs(result.Products))
+		if result.NextCursor == "" {
+			break
+		}
+		options.Cursor = result.NextCursor
+	}
+	if got := strings.Join(pages, " | "); got != "p0 p1 p2 p3 | p4 p5" {
+		t.Errorf("pages %q", got)
+	}
+
+	for _, cursor := range []string{"not a cursor", encodeCursor(-1), base64.RawURLEncoding.EncodeToString([]byte("page:2"))} {
+		if _, err := Search(products, SearchOptions{Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: error %v, want %v", cursor, err, ErrInvalidCursor)
+		}
+	}
+}
+
+// The baseline search matched a part of the name: "mac" still finds the MacBook of the catalog
+func TestSearchProductsByNameOnlyPrefix(t *testing.T) {
+	catalog, err := models.LoadCatalog("../products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := productIDs(SearchProductsByNameOnly(catalog.Products, "mac", 0)); got != "e003" {
+		t.Errorf("SearchProductsByNameOnly(\"mac\") = %q, want the MacBook Air M3 (e003)", got)
+	}
+}
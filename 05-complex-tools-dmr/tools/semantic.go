@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"one-tool/models"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Search modes
+const (
+	// SearchModeKeyword ranks the products with the full-text index (BM25), the default
+	SearchModeKeyword = "keyword"
+	// SearchModeSemantic ranks the products by the cosine similarity of their embeddings with the query
+	SearchModeSemantic = "semantic"
+	// SearchModeHybrid merges the keyword and the semantic rankings (reciprocal rank fusion)
+	SearchModeHybrid = "hybrid"
+)
+
+// DefaultMinSimilarity is the default minimum cosine similarity of a product matching a semantic search
+const DefaultMinSimilarity = 0.5
+
+// rrfK is the constant of the reciprocal rank fusion: a product scores 1/(rrfK+rank) in each ranking
+const rrfK = 60
+
+// ErrNoSemanticIndex is returned by a semantic or hybrid search without SemanticIndex
+var ErrNoSemanticIndex = errors.New("no semantic index")
+
+// Embedder computes the embeddings of texts (*engine.Engine with an embedding model)
+type Embedder interface {
+	Embed(texts []string) ([][]float64, error)
+}
+
+// SemanticIndex holds the embeddings of the name and the description of the products
+type SemanticIndex struct {
+	// MinSimilarity is the minimum cosine similarity of a matching product (DefaultMinSimilarity by default)
+	MinSimilarity float64
+	embedder      Embedder
+	vectors       [][]float64
+}
+
+// embeddingCache is the file of the embeddings of an embedder, by SHA-256 of the embedded text
+type embeddingCache struct {
+	Embedder string               `json:"embedder"`
+	Vectors  map[string][]float64 `json:"vectors"`
+}
+
+// NewSemanticIndex embeds the name and the description of the products
+// (the positions of the hits are their positions in this slice).
+// With a cachePath, the vectors are read from and saved to this JSON file,
+// so only the new or modified products are embedded; the cache of another embedder is ignored
+func NewSemanticIndex(products []models.Product, embedder Embedder, cachePath string) (*SemanticIndex, error) {
+	cache := embeddingCache{Embedder: embedderName(embedder), Vectors: map[string][]float64{}}
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var saved embeddingCache
+			if json.Unmarshal(data, &saved) == nil && saved.Embedder == cache.Embedder && saved.Vectors != nil {
+				cache.Vectors = saved.Vectors
+			}
+		}
+	}
+
+	keys := make([]string, len(products))
+	var missingKeys, missingTexts []string
+	for i, product := range products {
+		text := productText(product)
+		keys[i] = textKey(text)
+		if _, ok := cache.Vectors[keys[i]]; !ok && !slices.Contains(missingKeys, keys[i]) {
+			missingKeys = append(missingKeys, keys[i])
+			missingTexts = append(missingTexts, text)
+		}
+	}
+	if len(missingTexts) > 0 {
+		vectors, err := embedder.Embed(missingTexts)
+		if err != nil {
+			return nil, fmt.Errorf("error embedding the products: %w", err)
+		}
+		if len(vectors) != len(missingTexts) {
+			return nil, fmt.Errorf("error embedding the products: %d embeddings for %d products", len(vectors), len(missingTexts))
+		}
+		for i, key := range missingKeys {
+			cache.Vectors[key] = vectors[i]
+		}
+	}
+
+	index := &SemanticIndex{MinSimilarity: DefaultMinSimilarity, embedder: embedder, vectors: make([][]float64, len(products))}
+	used := map[string][]float64{}
+	for i, key := range keys {
+		index.vectors[i] = cache.Vectors[key]
+		used[key] = cache.Vectors[key]
+	}
+
+	// The cache keeps only the vectors of the current products
+	if cachePath != "" && (len(missingTexts) > 0 || len(used) != len(cache.Vectors)) {
+		cache.Vectors = used
+		data, err := json.Marshal(cache)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding the embeddings cache: %w", err)
+		}
+		if err := os.WriteFile(cachePath, data, 0644); err != nil {
+			return nil, fmt.Errorf("error writing the embeddings cache: %w", err)
+		}
+	}
+	return index, nil
+}
+
+// Search returns the products with a cosine similarity with the query of at least MinSimilarity,
+// the most similar first
+func (s *SemanticIndex) Search(query string) ([]models.Hit, error) {
+	vectors, err := s.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding the query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("error embedding the query: %d embeddings", len(vectors))
+	}
+
+	var hits []models.Hit
+	for position, vector := range s.vectors {
+		if similarity := cosine(vectors[0], vector); similarity >= s.MinSimilarity {
+			hits = append(hits, models.Hit{Position: position, Score: similarity})
+		}
+	}
+	sortHits(hits)
+	return hits, nil
+}
+
+// fuse merges rankings with the reciprocal rank fusion: the products ranked high in several rankings come first
+func fuse(rankings ...[]models.Hit) []models.Hit {
+	scores := map[int]float64{}
+	for _, ranking := range rankings {
+		for rank, hit := range ranking {
+			scores[hit.Position] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	hits := make([]models.Hit, 0, len(scores))
+	for position, score := range scores {
+		hits = append(hits, models.Hit{Position: position, Score: score})
+	}
+	sortHits(hits)
+	return hits
+}
+
+// sortHits sorts hits by score, then by position
+func sortHits(hits []models.Hit) {
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].Position < hits[b].Position
+	})
+}
+
+// cosine returns the cosine similarity of two vectors (0 if one of them is null or their sizes differ)
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// productText is the embedded text of a product
+func productText(product models.Product) string {
+	return product.Name + ": " + product.Description
+}
+
+// textKey is the key of a text in the embeddings cache
+func textKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// embedderName identifies the embedder of a cache (the provider and the model of an engine)
+func embedderName(embedder Embedder) string {
+	if stringer, ok := embedder.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%T", embedder)
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"one-tool/models"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// stubEmbedder is a deterministic embedder without model: the terms of the text (see models.Terms)
+// are hashed into a vector (feature hashing), so the texts sharing words are similar
+type stubEmbedder struct {
+	// Dimensions is the size of the vectors (256 by default)
+	Dimensions int
+}
+
+func (s stubEmbedder) Embed(texts []string) ([][]float64, error) {
+	dimensions := s.dimensions()
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, dimensions)
+		for _, term := range models.Terms(text) {
+			hash := fnv.New64a()
+			hash.Write([]byte(term))
+			sum := hash.Sum64()
+			sign := 1.0
+			if sum&(1<<63) != 0 {
+				sign = -1
+			}
+			vector[sum%uint64(dimensions)] += sign
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (s stubEmbedder) String() string {
+	return fmt.Sprintf("stub embedder (%d dimensions)", s.dimensions())
+}
+
+func (s stubEmbedder) dimensions() int {
+	if s.Dimensions <= 0 {
+		return 256
+	}
+	return s.Dimensions
+}
+
+// countingEmbedder counts the texts embedded by a stubEmbedder
+type countingEmbedder struct {
+	stubEmbedder
+	embedded int
+}
+
+func (c *countingEmbedder) Embed(texts []string) ([][]float64, error) {
+	c.embedded += len(texts)
+	return c.stubEmbedder.Embed(texts)
+}
+
+// hitIDs returns the IDs of the products of hits, separated by spaces
+func hitIDs(products []models.Product, hits []models.Hit) string {
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, products[hit.Position].ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestStubEmbedder(t *testing.T) {
+	vectors, err := stubEmbedder{}.Embed([]string{"Coffee Beans", "coffee bean", "Dune", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 4 || len(vectors[0]) != 256 {
+		t.Fatalf("%d vectors of %d dimensions, want 4 of 256", len(vectors), len(vectors[0]))
+	}
+	if similarity := cosine(vectors[0], vectors[1]); math.Abs(similarity-1) > 1e-9 {
+		t.Errorf("similarity of the same terms %f, want 1", similarity)
+	}
+	if similarity := cosine(vectors[0], vectors[2]); similarity != 0 {
+		t.Errorf("similarity without common terms %f, want 0", similarity)
+	}
+	if similarity := cosine(vectors[0], vectors[3]); similarity != 0 {
+		t.Errorf("similarity with an empty text %f, want 0", similarity)
+	}
+	if other, _ := (stubEmbedder{Dimensions: 8}).Embed([]string{"Dune"}); len(other[0]) != 8 {
+		t.Errorf("%d dimensions, want 8", len(other[0]))
+	}
+}
+
+func TestSemanticIndexSearch(t *testing.T) {
+	products := testProducts()
+	index, err := NewSemanticIndex(products, stubEmbedder{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query         string
+		minSimilarity float64
+		want          string
+	}{
+		{query: "coffee", minSimilarity: DefaultMinSimilarity, want: "p2"},
+		{query: "coffee", minSimilarity: 0.1, want: "p2 p3 p5"},
+		{query: "coffee beans", minSimilarity: DefaultMinSimilarity, want: "p3 p2"},
+		{query: "espresso cups coffee beans", minSimilarity: 0.1, want: "p5 p3 p2"},
+		{query: "laptop", minSimilarity: 0.1, want: ""},
+	}
+	for _, test := range tests {
+		index.MinSimilarity = test.minSimilarity
+		hits, err := index.Search(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hitIDs(products, hits); got != test.want {
+			t.Errorf("Search(%q) with a minimum similarity of %.1f = %q, want %q", test.query, test.minSimilarity, got, test.want)
+		}
+	}
+}
+
+func TestFuse(t *testing.T) {
+	tests := []struct {
+		name     string
+		rankings [][]int
+		want     []int
+	}{
+		{name: "single ranking", rankings: [][]int{{2, 0, 1}}, want: []int{2, 0, 1}},
+		{name: "same rankings", rankings: [][]int{{2, 0}, {2, 0}}, want: []int{2, 0}},
+		// 1 is second in both rankings: 2/62 is more than 1/61
+		{name: "agreement before a single first place", rankings: [][]int{{0, 1}, {2, 1}}, want: []int{1, 0, 2}},
+		// 0 and 2 have the same ranks: the first position comes first
+		{name: "ties by position", rankings: [][]int{{2, 0}, {0, 2}}, want: []int{0, 2}},
+		{name: "missing from a ranking", rankings: [][]int{{0, 1, 2}, {2}}, want: []int{2, 0, 1}},
+		{name: "no hits", rankings: [][]int{{}, {}}, want: []int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rankings := make([][]models.Hit, 0, len(test.rankings))
+			for _, positions := range test.rankings {
+				ranking := []models.Hit{}
+				for rank, position := range positions {
+					ranking = append(ranking, models.Hit{Position: position, Score: float64(len(positions) - rank)})
+				}
+				rankings = append(rankings, ranking)
+			}
+			got := []int{}
+			for _, hit := range fuse(rankings...) {
+				got = append(got, hit.Position)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("fuse = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSemanticIndexCache(t *testing.T) {
+	products := testProducts()
+	cachePath := filepath.Join(t.TempDir(), "embeddings.json")
+	build := func(embedder *countingEmbedder, products []models.Product) *SemanticIndex {
+		t.Helper()
+		index, err := NewSemanticIndex(products, embedder, cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	cachedVectors := func() int {
+		t.Helper()
+		data, err := os.ReadFile(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cache embeddingCache
+		if err := json.Unmarshal(data, &cache); err != nil {
+			t.Fatal(err)
+		}
+		return len(cache.Vectors)
+	}
+
+	// All the products are embedded the first time, none the second time
+	embedder := &countingEmbedder{}
+	build(embedder, products)
+	if embedder.embedded != len(products) || cachedVectors() != len(products) {
+		t.Fatalf("%d embedded and %d cached products, want %d", embedder.embedded, cachedVectors(), len(products))
+	}
+	embedder = &countingEmbedder{}
+	build(embedder, products)
+	if embedder.embedded != 0 {
+		t.Errorf("%d embedded products with the cache, want 0", embedder.embedded)
+	}
+
+	// Only a modified product is embedded again, and the cache forgets its previous text
+	products[3].Description = "Single-origin arabica beans"
+	embedder = &countingEmbedder{}
+	index := build(embedder, products)
+	if embedder.embedded != 1 || cachedVectors() != len(products) {
+		t.Errorf("%d embedded and %d cached products, want 1 and %d", embedder.embedded, cachedVectors(), len(products))
+	}
+	if hits, _ := index.Search("single origin arabica"); hitIDs(products, hits) != "p3" {
+		t.Errorf("the modified product is not found with its new description: %q", hitIDs(products, hits))
+	}
+
+	// A removed product is removed from the cache
+	embedder = &countingEmbedder{}
+	build(embedder, products[:4])
+	if embedder.embedded != 0 || cachedVectors() != 4 {
+		t.Errorf("%d embedded and %d cached products, want 0 and 4", embedder.embedded, cachedVectors())
+	}
+
+	// The cache of another embedder is ignored
+	embedder = &countingEmbedder{stubEmbedder: stubEmbedder{Dimensions: 64}}
+	build(embedder, products[:4])
+	if embedder.embedded != 4 {
+		t.Errorf("%d embedded products with the cache of another embedder, want 4", embedder.embedded)
+	}
+}
+
+func TestSearchModes(t *testing.T) {
+	products := testProducts()
+	semantic, err := NewSemanticIndex(products, stubEmbedder{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query         string
+		minSimilarity float64
+		// keyword, semantic and hybrid are the IDs of the results of each mode
+		keyword, semantic, hybrid string
+	}{
+		// The semantic search keeps only the similar products
+		{query: "coffee", minSimilarity: DefaultMinSimilarity, keyword: "p2 p3 p5", semantic: "p2", hybrid: "p2 p3 p5"},
+		// The keyword search matches the category, not the semantic search (name and description only):
+		// the hybrid search keeps the products of both rankings
+		{query: "sports backpack", minSimilarity: DefaultMinSimilarity, keyword: "p1 p0", semantic: "p1", hybrid: "p1 p0"},
+		// "home" ranks the espresso cups second with the keywords, the coffee beans are more similar:
+		// p3 and p5 have the same reciprocal ranks in the hybrid search (2nd and 3rd), by catalog order
+		{query: "home coffee", minSimilarity: 0.1, keyword: "p2 p5 p3", semantic: "p2 p3 p5", hybrid: "p2 p3 p5"},
+		{query: "laptop", minSimilarity: DefaultMinSimilarity},
+	}
+	for _, test := range tests {
+		semantic.MinSimilarity = test.minSimilarity
+		for mode, want := range map[string]string{
+			SearchModeKeyword:  test.keyword,
+			"":                 test.keyword,
+			SearchModeSemantic: test.semantic,
+			SearchModeHybrid:   test.hybrid,
+		} {
+			result, err := Search(products, SearchOptions{Query: test.query, Mode: mode, Semantic: semantic})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := productIDs(result.Products); got != want {
+				t.Errorf("%s search of %q = %q, want %q", mode, test.query, got, want)
+			}
+		}
+	}
+
+	for _, mode := range []string{SearchModeSemantic, SearchModeHybrid} {
+		if _, err := Search(products, SearchOptions{Query: "coffee", Mode: mode}); !errors.Is(err, ErrNoSemanticIndex) {
+			t.Errorf("%s search without semantic index: error %v, want %v", mode, err, ErrNoSemanticIndex)
+		}
+	}
+}
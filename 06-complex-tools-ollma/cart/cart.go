@@ -1,6 +1,7 @@
 package cart
 
 import (
+	"errors"
 	"fmt"
 	"one-tool/models"
 	"strings"
@@ -31,26 +32,31 @@ func (c *Cart) AddToCart(products []models.Product, productName string, quantity
 		return fmt.Errorf("quantity must be greater than 0")
 	}
 
-	// Find the product by name (case-insensitive exact match)
-	var productIndex = -1
-	var foundProduct models.Product
-
-	for i, product := range products {
-		if strings.EqualFold(product.Name, productName) {
-			productIndex = i
-			foundProduct = product
-			break
-		}
+	// Find the product by name (fuzzy match, see ResolveProduct)
+	match, err := ResolveProduct(products, productName)
+	if err != nil {
+		return err
 	}
+	return c.AddProduct(products, match, quantity)
+}
 
-	if productIndex == -1 {
-		return fmt.Errorf("product '%s' not found", productName)
+// AddProduct adds a product already resolved with ResolveProduct to the cart
+// (match.Index is the index of the product in products)
+// Returns error if insufficient stock
+func (c *Cart) AddProduct(products []models.Product, match Match, quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be greater than 0")
 	}
+	if match.Index < 0 || match.Index >= len(products) || products[match.Index].ID != match.Product.ID {
+		return fmt.Errorf("product '%s' not found in inventory", match.Product.Name)
+	}
+	productIndex := match.Index
+	foundProduct := products[productIndex]
 
 	// Check if there's enough stock
 	if foundProduct.Stock < quantity {
 		return fmt.Errorf("insufficient stock for '%s'. Available: %d, Requested: %d",
-			productName, foundProduct.Stock, quantity)
+			foundProduct.Name, foundProduct.Stock, quantity)
 	}
 
 	// Update stock in the products slice
@@ -83,18 +89,26 @@ func (c *Cart) UpdateCartQuantity(products []models.Product, productName string,
 		return fmt.Errorf("quantity cannot be negative")
 	}
 
-	// Find the item in cart
-	cartItemIndex := -1
-	for i, item := range c.Items {
-		if strings.EqualFold(item.Product.Name, productName) {
-			cartItemIndex = i
-			break
-		}
+	// Find the item in cart (fuzzy match, see ResolveItem)
+	match, err := c.ResolveItem(productName)
+	if err != nil {
+		return err
 	}
+	return c.UpdateItem(products, match, newQuantity)
+}
 
-	if cartItemIndex == -1 {
-		return fmt.Errorf("product '%s' not found in cart", productName)
+// UpdateItem updates the quantity of a cart item already resolved with ResolveItem
+// (match.Index is the index of the cart item)
+// If newQuantity is 0, the item is removed from the cart
+// Returns error if insufficient stock
+func (c *Cart) UpdateItem(products []models.Product, match Match, newQuantity int) error {
+	if newQuantity < 0 {
+		return fmt.Errorf("quantity cannot be negative")
 	}
+	if !c.hasItem(match) {
+		return fmt.Errorf("%w in cart: '%s'", ErrProductNotFound, match.Product.Name)
+	}
+	cartItemIndex := match.Index
 
 	cartItem := c.Items[cartItemIndex]
 	currentQuantity := cartItem.Quantity
@@ -110,14 +124,14 @@ func (c *Cart) UpdateCartQuantity(products []models.Product, productName string,
 	}
 
 	if productIndex == -1 {
-		return fmt.Errorf("product '%s' not found in inventory", productName)
+		return fmt.Errorf("product '%s' not found in inventory", cartItem.Product.Name)
 	}
 
 	// If increasing quantity, check if enough stock available
 	if quantityDifference > 0 {
 		if products[productIndex].Stock < quantityDifference {
-			return fmt.Errorf("insufficient stock for '%s'. Available: %d, Additional needed: %d", 
-				productName, products[productIndex].Stock, quantityDifference)
+			return fmt.Errorf("insufficient stock for '%s'. Available: %d, Additional needed: %d",
+				cartItem.Product.Name, products[productIndex].Stock, quantityDifference)
 		}
 	}
 
@@ -136,26 +150,30 @@ func (c *Cart) UpdateCartQuantity(products []models.Product, productName string,
 	return nil
 }
 
-
-
 // RemoveFromCart removes a product from the cart and restores stock
 func (c *Cart) RemoveFromCart(products []models.Product, productName string, quantity int) error {
 	if quantity <= 0 {
 		return fmt.Errorf("quantity must be greater than 0")
 	}
 
-	// Find the item in cart
-	cartItemIndex := -1
-	for i, item := range c.Items {
-		if strings.EqualFold(item.Product.Name, productName) {
-			cartItemIndex = i
-			break
-		}
+	// Find the item in cart (fuzzy match, see ResolveItem)
+	match, err := c.ResolveItem(productName)
+	if err != nil {
+		return err
 	}
+	return c.RemoveItem(products, match, quantity)
+}
 
-	if cartItemIndex == -1 {
-		return fmt.Errorf("product '%s' not found in cart", productName)
+// RemoveItem removes a quantity of a cart item already resolved with ResolveItem
+// (match.Index is the index of the cart item) and restores stock
+func (c *Cart) RemoveItem(products []models.Product, match Match, quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be greater than 0")
 	}
+	if !c.hasItem(match) {
+		return fmt.Errorf("%w in cart: '%s'", ErrProductNotFound, match.Product.Name)
+	}
+	cartItemIndex := match.Index
 
 	cartItem := c.Items[cartItemIndex]
 
@@ -183,6 +201,25 @@ func (c *Cart) RemoveFromCart(products []models.Product, productName string, qua
 	return nil
 }
 
+// hasItem checks that a match of ResolveItem is still the cart item at its index
+func (c *Cart) hasItem(match Match) bool {
+	return match.Index >= 0 && match.Index < len(c.Items) && c.Items[match.Index].Product.ID == match.Product.ID
+}
+
+// ResolveItem finds the cart item of a requested product name, like ResolveProduct
+// with the products of the cart (Match.Index is the index of the cart item)
+func (c *Cart) ResolveItem(productName string) (Match, error) {
+	products := make([]models.Product, 0, len(c.Items))
+	for _, item := range c.Items {
+		products = append(products, item.Product)
+	}
+	match, err := ResolveProduct(products, productName)
+	if errors.Is(err, ErrProductNotFound) {
+		return Match{}, fmt.Errorf("%w in cart: '%s'", ErrProductNotFound, productName)
+	}
+	return match, err
+}
+
 // GetCartTotal calculates the total price of items in the cart
 func (c *Cart) GetCartTotal() float64 {
 	total := 0.0
@@ -0,0 +1,201 @@
+package cart
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"one-tool/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MinConfidence is the minimum confidence of a candidate
+	MinConfidence = 0.3
+	// AcceptConfidence is the minimum confidence to resolve a name without asking
+	AcceptConfidence = 0.75
+	// AmbiguityMargin is the minimum lead of the best match over the second one to resolve a name without asking
+	AmbiguityMargin = 0.15
+	// MaxCandidates is the maximum number of candidates of an ambiguous name
+	MaxCandidates = 5
+)
+
+// ErrProductNotFound is returned when no product is close to the requested name
+var ErrProductNotFound = errors.New("product not found")
+
+// Match is a product matching a requested name
+type Match struct {
+	// Index is the index of the product in the searched products
+	Index   int
+	Product models.Product
+	// Confidence is the score of the match, from 0 to 1 (1 for an exact match)
+	Confidence float64
+}
+
+// AmbiguousProductError is returned when a requested name matches several products
+// (or a single one with a low confidence): the model has to choose one of the candidates
+type AmbiguousProductError struct {
+	Name       string
+	Candidates []Match
+}
+
+func (e *AmbiguousProductError) Error() string {
+	candidates := make([]string, 0, len(e.Candidates))
+	for _, candidate := range e.Candidates {
+		candidates = append(candidates, fmt.Sprintf("'%s' (%.0f%%)", candidate.Product.Name, 100*candidate.Confidence))
+	}
+	return fmt.Sprintf("product '%s' is ambiguous, use the exact name of one of these products: %s",
+		e.Name, strings.Join(candidates, ", "))
+}
+
+// ResolveProduct finds the product of a requested name, e.g. "ipad pro" for "iPad Pro 12.9".
+// The match is case-insensitive and tolerant: the words of the name are compared
+// with the words of the product names (and categories) by prefix and with typos,
+// the rare words weighing more than the common ones (e.g. "macbook" more than "pro").
+// It returns ErrProductNotFound when no product is close,
+// and an *AmbiguousProductError with the candidates when the best match is not clear
+func ResolveProduct(products []models.Product, name string) (Match, error) {
+	candidates := Candidates(products, name)
+	switch {
+	case len(candidates) == 0:
+		return Match{}, fmt.Errorf("%w: '%s'", ErrProductNotFound, name)
+	case candidates[0].Confidence == 1:
+		return candidates[0], nil
+	case candidates[0].Confidence >= AcceptConfidence &&
+		(len(candidates) == 1 || candidates[0].Confidence-candidates[1].Confidence >= AmbiguityMargin):
+		return candidates[0], nil
+	}
+	return Match{}, &AmbiguousProductError{Name: name, Candidates: candidates[:min(len(candidates), MaxCandidates)]}
+}
+
+// Candidates returns the products matching a requested name with at least MinConfidence,
+// the best matches first
+func Candidates(products []models.Product, name string) []Match {
+	query := tokenize(name)
+	if len(query) == 0 {
+		return nil
+	}
+
+	// Inverse document frequency of the words of the product names
+	frequencies := map[string]int{}
+	productTokens := make([][]string, len(products))
+	for i, product := range products {
+		productTokens[i] = tokenize(product.Name)
+		seen := map[string]bool{}
+		for _, token := range productTokens[i] {
+			if !seen[token] {
+				frequencies[token]++
+				seen[token] = true
+			}
+		}
+	}
+	weight := func(token string) float64 {
+		// The unknown words weigh as much as the rarest ones
+		return math.Log(1 + float64(len(products))/float64(max(frequencies[token], 1)))
+	}
+
+	var matches []Match
+	for i, product := range products {
+		if strings.EqualFold(strings.TrimSpace(name), product.Name) {
+			matches = append(matches, Match{Index: i, Product: product, Confidence: 1})
+			continue
+		}
+
+		// The category words help a match ("sapiens book") without being required
+		categoryTokens := tokenize(product.Category)
+
+		// Weighted ratio of the words of the query found in the product
+		found, total := 0.0, 0.0
+		matched := map[int]bool{}
+		for _, token := range query {
+			total += weight(token)
+			best, bestIndex := 0.0, -1
+			for j, productToken := range productTokens[i] {
+				if similarity := tokenSimilarity(token, productToken); similarity > best {
+					best, bestIndex = similarity, j
+				}
+			}
+			for _, categoryToken := range categoryTokens {
+				if similarity := tokenSimilarity(token, categoryToken); similarity > best {
+					best, bestIndex = similarity, -1
+				}
+			}
+			found += best * weight(token)
+			if bestIndex >= 0 && best > 0 {
+				matched[bestIndex] = true
+			}
+		}
+		queryCoverage := found / total
+		// Ratio of the words of the product name found in the query
+		productCoverage := float64(len(matched)) / float64(max(len(productTokens[i]), 1))
+
+		confidence := 0.8*queryCoverage + 0.2*productCoverage
+		// Only an exact match is certain
+		confidence = math.Min(confidence, 0.99)
+		if queryCoverage > 0 && confidence >= MinConfidence {
+			matches = append(matches, Match{Index: i, Product: product, Confidence: confidence})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Confidence > matches[b].Confidence
+	})
+	return matches
+}
+
+// tokenize returns the lowercase words of a text
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+}
+
+// tokenSimilarity compares a word of the query with a word of a product: 1 when equal,
+// 0.9 for an abbreviation ("mac" and "macbook") or a plural ("books" and "book"),
+// a score for a few typos ("ipda" and "ipad"), 0 otherwise
+func tokenSimilarity(query, word string) float64 {
+	switch {
+	case query == word:
+		return 1
+	case len(query) >= 3 && strings.HasPrefix(word, query),
+		len(word) >= 3 && strings.HasPrefix(query, word) && len(query)-len(word) <= 2:
+		return 0.9
+	}
+	longest := max(len([]rune(query)), len([]rune(word)))
+	if longest < 4 {
+		return 0
+	}
+	similarity := 1 - float64(editDistance(query, word))/float64(longest)
+	if similarity < 0.75 {
+		return 0
+	}
+	return similarity * 0.9
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent letters between two words
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	distances := make([][]int, len(ra)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(rb)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(ra)][len(rb)]
+}
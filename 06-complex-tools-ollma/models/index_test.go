@@ -0,0 +1,118 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"books":     "book",
+		"stories":   "story",
+		"glasses":   "glass",
+		"watches":   "watch",
+		"boxes":     "box",
+		"running":   "run",
+		"swimming":  "swim",
+		"jumped":    "jump",
+		"quickly":   "quick",
+		"shoes":     "shoe",
+		"status":    "status",
+		"analysis":  "analysis",
+		"bus":       "bus",
+		"pro":       "pro",
+		"wireless":  "wireless",
+		"headphone": "headphone",
+	}
+	for word, want := range tests {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "iPad Pro 12.9", want: "ipad pro 12 9"},
+		{text: "I'm looking for some running shoes", want: "i'm run shoe"},
+		{text: "The Kid's Books", want: "kid book"},
+		{text: "the and of", want: ""},
+	}
+	for _, test := range tests {
+		if got := strings.Join(Terms(test.text), " "); got != test.want {
+			t.Errorf("Terms(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	products := []Product{
+		{ID: "p0", Name: "Running Shoes", Description: "Lightweight shoes for the road", Category: "sports"},
+		{ID: "p1", Name: "Trail Backpack", Description: "A backpack for running and hiking", Category: "sports"},
+		{ID: "p2", Name: "Coffee Maker", Description: "Brews coffee for the whole family", Category: "home"},
+		{ID: "p3", Name: "Coffee Beans", Description: "Arabica beans", Category: "food"},
+		{ID: "p4", Name: "Dune", Description: "Science fiction novel", Category: "books"},
+	}
+	index := NewIndex(products)
+	tests := []struct {
+		name  string
+		query string
+		// want are the IDs of the hits, the most relevant first
+		want string
+	}{
+		{name: "name before description", query: "running", want: "p0 p1"},
+		{name: "plural and stem", query: "shoe", want: "p0"},
+		{name: "rare term first", query: "coffee beans", want: "p3 p2"},
+		{name: "term frequency", query: "coffee", want: "p2 p3"},
+		{name: "category", query: "books", want: "p4"},
+		{name: "case-insensitive", query: "DUNE", want: "p4"},
+		{name: "stop words only", query: "show me the", want: ""},
+		{name: "no match", query: "laptop", want: ""},
+		{name: "prefix", query: "back", want: "p1"},
+		{name: "prefix of several terms", query: "coff", want: "p2 p3"},
+		{name: "term before prefix", query: "dune coff", want: "p4 p2 p3"},
+		{name: "single letter", query: "d", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := []string{}
+			previous := 0.0
+			for i, hit := range index.Search(test.query) {
+				if hit.Score <= 0 || (i > 0 && hit.Score > previous) {
+					t.Errorf("hit %d of %s has the score %f after %f", i, products[hit.Position].ID, hit.Score, previous)
+				}
+				previous = hit.Score
+				ids = append(ids, products[hit.Position].ID)
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("Search(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIndexSearchEmpty(t *testing.T) {
+	for _, products := range [][]Product{nil, {{ID: "p0", Name: "The", Description: "of the"}}} {
+		if hits := NewIndex(products).Search("the dune"); len(hits) != 0 {
+			t.Errorf("hits %v in an index without terms", hits)
+		}
+	}
+}
+
+// The baseline search matched a part of the name: "mac" finds the MacBook of the catalog
+func TestIndexSearchCatalogPrefix(t *testing.T) {
+	catalog, err := LoadCatalog("../products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, hit := range catalog.Index.Search("mac") {
+		names = append(names, catalog.Products[hit.Position].Name)
+	}
+	if strings.Join(names, ", ") != "MacBook Air M3" {
+		t.Errorf("Search(\"mac\") = %v, want the MacBook Air M3", names)
+	}
+}
@@ -12,14 +12,29 @@ type Product struct {
 	Description string  `json:"Description"`
 	Price       float64 `json:"Price"`
 	Category    string  `json:"Category"`
+	Subcategory string  `json:"Subcategory,omitempty"` // optional, e.g. "phones" in "electronics"
 	Stock       int     `json:"Stock"`
 }
 
 type ProductCatalog struct {
 	Products []Product `json:"products"`
+	// Index is the full-text search index of the products
+	Index *Index `json:"-"`
+	// Taxonomy is the categories and the sub-categories of the products
+	Taxonomy *Taxonomy `json:"-"`
 }
 
+// LoadProducts reads the products of a catalog file and indexes them (see LoadCatalog)
 func LoadProducts(filename string) ([]Product, error) {
+	catalog, err := LoadCatalog(filename)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Products, nil
+}
+
+// LoadCatalog reads the products of a catalog file and builds their search index and their taxonomy
+func LoadCatalog(filename string) (*ProductCatalog, error) {
 	// Read the JSON file
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -33,5 +48,7 @@ func LoadProducts(filename string) ([]Product, error) {
 		return nil, fmt.Errorf("error parsing JSON: %v", err)
 	}
 
-	return catalog.Products, nil
+	catalog.Index = NewIndex(catalog.Products)
+	catalog.Taxonomy = NewTaxonomy(catalog.Products)
+	return &catalog, nil
 }
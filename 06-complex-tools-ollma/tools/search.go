@@ -1,46 +1,216 @@
 package tools
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"one-tool/models"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// SearchProducts searches for products by name and/or category with optional limit
-// name: partial or full product name (case-insensitive), empty string to ignore
-// category: exact category match (case-insensitive), empty string to ignore
-// limit: maximum number of results to return, 0 or negative for no limit
-func SearchProducts(products []models.Product, name, category string, limit int) []models.Product {
-	var results []models.Product
+// Sort orders of the search results
+const (
+	SortByRelevance = "relevance"
+	SortByPriceAsc  = "price_asc"
+	SortByPriceDesc = "price_desc"
+	SortByName      = "name"
+)
+
+// ErrInvalidCursor is returned when the cursor of a search is not a cursor of a previous search
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SearchOptions are the criteria, the sort order and the page of a search
+type SearchOptions struct {
+	// Query is a full-text search of the name, the description and the category:
+	// the products matching a word of the query or a word starting with it, ranked by relevance (BM25)
+	Query string
+	// Index is the index of the searched products (see models.LoadCatalog),
+	// built for the search when nil
+	Index *models.Index
+	// Mode is the ranking of the query: keyword (default), semantic or hybrid
+	Mode string
+	// Semantic is the semantic index of the searched products, for the semantic and hybrid modes
+	Semantic *SemanticIndex
+	// Category is an exact category match (case-insensitive), or a sub-category match
+	// with a category path ("electronics/phones", see models.Taxonomy), empty to ignore
+	Category string
+	// MinPrice and MaxPrice are the price range (0 to ignore a bound)
+	MinPrice float64
+	MaxPrice float64
+	// InStock keeps only the products in stock
+	InStock bool
+	// SortBy is the order of the results: relevance (default), price_asc, price_desc or name
+	SortBy string
+	// Offset is the number of results to skip
+	Offset int
+	// Cursor is the NextCursor of the previous page (it replaces Offset)
+	Cursor string
+	// Limit is the maximum number of results, 0 or negative for no limit
+	Limit int
+}
+
+// SearchResult is a page of search results
+type SearchResult struct {
+	Products []models.Product
+	// Total is the number of matching products (all the pages)
+	Total int
+	// Offset is the index of the first product of the page in all the results
+	Offset int
+	// NextCursor is the cursor of the next page, empty for the last page
+	NextCursor string
+}
+
+// Search searches for products by query (name, description and category), category, price range and stock,
+// sorts the results and returns the requested page (the filters, the sort and the page apply after the ranking)
+func Search(products []models.Product, options SearchOptions) (SearchResult, error) {
+	offset := max(options.Offset, 0)
+	if options.Cursor != "" {
+		var err error
+		offset, err = decodeCursor(options.Cursor)
+		if err != nil {
+			return SearchResult{}, err
+		}
+	}
 
-	// Convert search terms to lowercase for case-insensitive comparison
-	searchName := strings.ToLower(name)
-	searchCategory := strings.ToLower(category)
+	ranked, err := rank(products, options)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	var matches []scoredProduct
+	for _, candidate := range ranked {
+		product := candidate.product
+		if options.Category != "" && !matchCategory(product, options.Category) {
+			continue
+		}
+		if options.MinPrice > 0 && product.Price < options.MinPrice {
+			continue
+		}
+		if options.MaxPrice > 0 && product.Price > options.MaxPrice {
+			continue
+		}
+		if options.InStock && product.Stock <= 0 {
+			continue
+		}
+		matches = append(matches, candidate)
+	}
 
-	for _, product := range products {
-		// Check if we've reached the limit
-		if limit > 0 && len(results) >= limit {
-			break
+	sort.SliceStable(matches, func(i, j int) bool {
+		switch options.SortBy {
+		case SortByPriceAsc:
+			return matches[i].product.Price < matches[j].product.Price
+		case SortByPriceDesc:
+			return matches[i].product.Price > matches[j].product.Price
+		case SortByName:
+			return strings.ToLower(matches[i].product.Name) < strings.ToLower(matches[j].product.Name)
+		default:
+			return matches[i].score > matches[j].score
 		}
+	})
+
+	result := SearchResult{Total: len(matches), Offset: offset}
+	end := len(matches)
+	if options.Limit > 0 {
+		end = min(offset+options.Limit, len(matches))
+	}
+	for i := offset; i < end; i++ {
+		result.Products = append(result.Products, matches[i].product)
+	}
+	if end < len(matches) {
+		result.NextCursor = encodeCursor(end)
+	}
+	return result, nil
+}
+
+// matchCategory tells if a product is in a category ("electronics") or a sub-category ("electronics/phones")
+func matchCategory(product models.Product, category string) bool {
+	name, subcategory, _ := strings.Cut(category, models.SubcategorySeparator)
+	return strings.EqualFold(product.Category, strings.TrimSpace(name)) &&
+		(subcategory == "" || strings.EqualFold(product.Subcategory, strings.TrimSpace(subcategory)))
+}
 
-		productName := strings.ToLower(product.Name)
-		productCategory := strings.ToLower(product.Category)
+// scoredProduct is a product with its relevance to the query
+type scoredProduct struct {
+	product models.Product
+	score   float64
+}
 
-		// Check name match (partial match if name is provided)
-		nameMatch := name == "" || strings.Contains(productName, searchName)
+// rank returns the products matching the query, the most relevant first (depending on the search mode),
+// or all the products in the catalog order without query
+func rank(products []models.Product, options SearchOptions) ([]scoredProduct, error) {
+	if strings.TrimSpace(options.Query) == "" {
+		ranked := make([]scoredProduct, 0, len(products))
+		for _, product := range products {
+			ranked = append(ranked, scoredProduct{product: product})
+		}
+		return ranked, nil
+	}
 
-		// Check category match (exact match if category is provided)
-		categoryMatch := category == "" || productCategory == searchCategory
+	keywordHits := func() []models.Hit {
+		index := options.Index
+		if index == nil {
+			index = models.NewIndex(products)
+		}
+		return index.Search(options.Query)
+	}
+	var hits []models.Hit
+	switch options.Mode {
+	case SearchModeSemantic, SearchModeHybrid:
+		if options.Semantic == nil {
+			return nil, fmt.Errorf("%w for the %s search", ErrNoSemanticIndex, options.Mode)
+		}
+		semanticHits, err := options.Semantic.Search(options.Query)
+		if err != nil {
+			return nil, err
+		}
+		hits = semanticHits
+		if options.Mode == SearchModeHybrid {
+			hits = fuse(keywordHits(), semanticHits)
+		}
+	default:
+		hits = keywordHits()
+	}
 
-		// Include product if both conditions are met
-		if nameMatch && categoryMatch {
-			results = append(results, product)
+	ranked := make([]scoredProduct, 0, len(hits))
+	for _, hit := range hits {
+		if hit.Position < len(products) {
+			ranked = append(ranked, scoredProduct{product: products[hit.Position], score: hit.Score})
 		}
 	}
+	return ranked, nil
+}
+
+// encodeCursor returns the opaque cursor of an offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
 
-	return results
+// decodeCursor returns the offset of a cursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	value, ok := strings.CutPrefix(string(data), "offset:")
+	offset, err := strconv.Atoi(value)
+	if !ok || err != nil || offset < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	return offset, nil
+}
+
+// SearchProducts searches for products by name or description and/or category with optional limit
+// name: words (or beginnings of words, "mac" for "MacBook") of the product name or description, the most relevant products first, empty string to ignore
+// category: exact category match (case-insensitive), empty string to ignore
+// limit: maximum number of results to return, 0 or negative for no limit
+func SearchProducts(products []models.Product, name, category string, limit int) []models.Product {
+	result, _ := Search(products, SearchOptions{Query: name, Category: category, Limit: limit})
+	return result.Products
 }
 
-// SearchProductsByNameOnly searches products by name only (partial match) with optional limit
+// SearchProductsByNameOnly searches products by the words of their name or description only
+// (ranked term or prefix match, the most relevant products first) with optional limit
 func SearchProductsByNameOnly(products []models.Product, name string, limit int) []models.Product {
 	return SearchProducts(products, name, "", limit)
 }
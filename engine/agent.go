@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"errors"
+
+	"github.com/openai/openai-go"
+)
+
+// DefaultMaxTurns is the default maximum number of tool completions of Run
+const DefaultMaxTurns = 10
+
+// ErrMaxTurnsReached is returned by Run when the model still asks for tools after the last turn
+var ErrMaxTurnsReached = errors.New("maximum number of turns reached")
+
+// ToolExecutor executes a tool call and returns the content of the tool message
+type ToolExecutor func(toolCall openai.ChatCompletionMessageToolCall) (string, error)
+
+// RunResult is the result of an agent loop
+type RunResult struct {
+	// Content is the final answer of the model (without tool calls)
+	Content string
+	// Messages is the whole conversation: the initial messages,
+	// the assistant messages and the tool messages
+	Messages []openai.ChatCompletionMessageParamUnion
+	// ToolCalls are all the tool calls executed during the loop
+	ToolCalls []openai.ChatCompletionMessageToolCall
+	// Turns is the number of completions, with the last one
+	// (the final answer without tool calls, when the loop ends before the maximum number of turns)
+	Turns int
+}
+
+// Run is an agent loop on top of the tool completion:
+// it executes the detected tool calls with the executor, appends the assistant message
+// and the tool messages (the content or the error of every tool call) to the conversation,
+// and calls the model again
+// until it answers without tool calls or until the maximum number of turns is reached
+func (e *Engine) Run(messages []openai.ChatCompletionMessageParamUnion, executor ToolExecutor, options ...CompletionOption) (RunResult, error) {
+	result := RunResult{
+		Messages: append([]openai.ChatCompletionMessageParamUnion{}, messages...),
+	}
+
+	for result.Turns < e.maxTurns {
+		completion, err := e.CompleteWithTools(result.Messages, options...)
+		if err != nil {
+			return result, err
+		}
+		result.Turns++
+
+		// The model answered without tool calls: this is the end of the loop
+		if len(completion.ToolCalls) == 0 {
+			result.Content = completion.Content
+			result.Messages = append(result.Messages, openai.AssistantMessage(completion.Content))
+			return result, nil
+		}
+
+		result.Messages = append(result.Messages, completion.Message.ToParam())
+
+		for _, toolCall := range completion.ToolCalls {
+			// A failed tool call is answered with the error, so the model can correct itself
+			content, err := executor(toolCall)
+			if err != nil {
+				content = ToolErrorContent(toolCall, err)
+			}
+			result.ToolCalls = append(result.ToolCalls, toolCall)
+			result.Messages = append(result.Messages, openai.ToolMessage(content, toolCall.ID))
+		}
+	}
+
+	return result, ErrMaxTurnsReached
+}
+
+// ToolCallsMessage returns the assistant message carrying the tool calls,
+// to add to the conversation before the tool messages answering them
+func ToolCallsMessage(toolCalls []openai.ChatCompletionMessageToolCall) openai.ChatCompletionMessageParamUnion {
+	message := openai.ChatCompletionMessage{
+		Role:      "assistant",
+		ToolCalls: toolCalls,
+	}
+	return message.ToParam()
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// agentHandler answers the completions with the tool calls of each turn, then with a final answer,
+// and records the messages of every request
+func agentHandler(turns [][]openai.ChatCompletionMessageToolCall, requests *[][]map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		*requests = append(*requests, body.Messages)
+		if turn := len(*requests) - 1; turn < len(turns) {
+			writeCompletion(w, "tool_calls", "", turns[turn]...)
+			return
+		}
+		writeCompletion(w, "stop", "Your cart has 2 Dune books")
+	}
+}
+
+func TestEngineRun(t *testing.T) {
+	var requests [][]map[string]any
+	runEngine := testEngine(t, agentHandler([][]openai.ChatCompletionMessageToolCall{
+		{toolCall("add_to_cart", `{"product_name":"Dune","quantity":2}`), toolCall("fly_to_mars", `{}`)},
+	}, &requests))
+	registry := NewRegistry(NewTool("add_to_cart", "Add a product to the cart",
+		func(args struct {
+			ProductName string `json:"product_name"`
+			Quantity    int    `json:"quantity"`
+		}) (string, error) {
+			return "Added", nil
+		},
+	))
+
+	result, err := runEngine.Run([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("add 2 Dune")}, registry.Execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The completion with the tool calls, then the final answer
+	if result.Turns != 2 || len(requests) != 2 {
+		t.Errorf("turns %d, requests %d, want 2", result.Turns, len(requests))
+	}
+	if result.Content != "Your cart has 2 Dune books" {
+		t.Errorf("content %q", result.Content)
+	}
+	if len(result.ToolCalls) != 2 {
+		t.Errorf("tool calls %d, want 2", len(result.ToolCalls))
+	}
+	// user, assistant (tool calls), 2 tool messages, assistant (final answer)
+	if len(result.Messages) != 5 {
+		t.Fatalf("messages %d, want 5", len(result.Messages))
+	}
+
+	// The second request has the answers of both tool calls, the unknown tool with an error
+	messages := requests[1]
+	if len(messages) != 4 || messages[1]["role"] != "assistant" {
+		t.Fatalf("messages of the second request %v", messages)
+	}
+	toolCalls, _ := messages[1]["tool_calls"].([]any)
+	if len(toolCalls) != 2 {
+		t.Errorf("tool calls of the assistant message %v", messages[1]["tool_calls"])
+	}
+	if messages[2]["role"] != "tool" || messages[2]["tool_call_id"] != "call_0" || messages[2]["content"] != "Added" {
+		t.Errorf("tool message %v", messages[2])
+	}
+	content, _ := messages[3]["content"].(string)
+	var toolError ToolError
+	if err := json.Unmarshal([]byte(content), &toolError); err != nil {
+		t.Fatalf("tool message %q is not a tool error: %v", content, err)
+	}
+	if messages[3]["tool_call_id"] != "call_1" || toolError.Tool != "fly_to_mars" || !strings.Contains(toolError.Message, ErrUnknownTool.Error()) {
+		t.Errorf("tool message %v", messages[3])
+	}
+}
+
+func TestEngineRunMaxTurns(t *testing.T) {
+	var requests [][]map[string]any
+	viewCart := []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`)}
+	runEngine := testEngine(t, agentHandler([][]openai.ChatCompletionMessageToolCall{viewCart, viewCart, viewCart}, &requests), WithMaxTurns(2))
+	executions := 0
+
+	result, err := runEngine.Run([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("view the cart")},
+		func(toolCall openai.ChatCompletionMessageToolCall) (string, error) {
+			executions++
+			return "empty cart", nil
+		})
+	if !errors.Is(err, ErrMaxTurnsReached) {
+		t.Fatalf("error %v, want %v", err, ErrMaxTurnsReached)
+	}
+	if result.Turns != 2 || len(requests) != 2 || executions != 2 {
+		t.Errorf("turns %d, requests %d, executions %d, want 2", result.Turns, len(requests), executions)
+	}
+	// The conversation so far: user, then an assistant and a tool message per turn
+	if result.Content != "" || len(result.Messages) != 5 {
+		t.Errorf("content %q, messages %d, want 5", result.Content, len(result.Messages))
+	}
+}
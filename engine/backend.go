@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+// backend sends the completion requests of the engine to the provider
+type backend interface {
+	// complete runs a completion
+	complete(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error)
+	// stream runs a streaming completion and calls onChunk for each chunk
+	stream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(chunk openai.ChatCompletionChunk)) error
+	// listModels returns the names of the models available on the provider
+	listModels(ctx context.Context) ([]string, error)
+	// embed returns the embeddings of the inputs, in the order of the inputs
+	embed(ctx context.Context, model string, inputs []string) ([][]float64, error)
+}
+
+// openAIBackend talks to the OpenAI-compatible API of the provider
+type openAIBackend struct {
+	client openai.Client
+}
+
+func (b openAIBackend) complete(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	return b.client.Chat.Completions.New(ctx, params)
+}
+
+func (b openAIBackend) stream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(chunk openai.ChatCompletionChunk)) error {
+	stream := b.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	for stream.Next() {
+		onChunk(stream.Current())
+	}
+	return stream.Err()
+}
+
+func (b openAIBackend) listModels(ctx context.Context) ([]string, error) {
+	page, err := b.client.Models.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]string, 0, len(page.Data))
+	for _, model := range page.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+func (b openAIBackend) embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	response, err := b.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs},
+		Model: model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float64, len(inputs))
+	for _, embedding := range response.Data {
+		if embedding.Index < 0 || int(embedding.Index) >= len(inputs) {
+			return nil, fmt.Errorf("%w: index %d of %d inputs", ErrInvalidEmbeddings, embedding.Index, len(inputs))
+		}
+		embeddings[embedding.Index] = embedding.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// Status of a compared tool call or of a compared argument
+const (
+	// StatusEqual: the same on both sides
+	StatusEqual = "equal"
+	// StatusDifferent: on both sides, with different values
+	StatusDifferent = "different"
+	// StatusMissing: only on the left side (missing on the right side)
+	StatusMissing = "missing"
+	// StatusExtra: only on the right side
+	StatusExtra = "extra"
+)
+
+// Comparison is the report of the comparison of two lists of tool calls (e.g. DMR vs Ollama).
+// It can be printed as text (String) or marshalled as JSON
+type Comparison struct {
+	// Left and Right are the names of the compared sides
+	Left  string `json:"left"`
+	Right string `json:"right"`
+	// Equal is true when the tool calls are the same, whatever their order
+	Equal bool `json:"equal"`
+	// Calls are the compared tool calls: the equal ones, then the different, missing and extra ones
+	Calls []ToolCallDiff `json:"calls"`
+}
+
+// ToolCallDiff is a compared tool call
+type ToolCallDiff struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Left and Right are the normalized arguments of each side (when the call exists on this side)
+	Left  json.RawMessage `json:"left,omitempty"`
+	Right json.RawMessage `json:"right,omitempty"`
+	// Fields are the differing arguments of a different tool call
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff is a differing argument of a tool call
+type FieldDiff struct {
+	// Field is the path of the argument (e.g. "product.name")
+	Field  string          `json:"field"`
+	Status string          `json:"status"`
+	Left   json.RawMessage `json:"left,omitempty"`
+	Right  json.RawMessage `json:"right,omitempty"`
+}
+
+// CompareToolCalls compares two lists of tool calls whatever their order.
+// The arguments are normalized before the comparison (key order, number formats like 10 vs 10.0, whitespace).
+// The tool calls of the same name which are not equal are paired by similarity
+// and reported as different with their differing arguments
+func CompareToolCalls(leftName string, left []openai.ChatCompletionMessageToolCall, rightName string, right []openai.ChatCompletionMessageToolCall) Comparison {
+	comparison := Comparison{Left: leftName, Right: rightName, Equal: true}
+
+	leftCalls := normalizeToolCalls(left)
+	rightCalls := normalizeToolCalls(right)
+
+	// The equal tool calls, whatever their order
+	var leftRest []normalizedToolCall
+	for _, call := range leftCalls {
+		index := slices.IndexFunc(rightCalls, func(other normalizedToolCall) bool {
+			return other.name == call.name && other.arguments == call.arguments
+		})
+		if index < 0 {
+			leftRest = append(leftRest, call)
+			continue
+		}
+		comparison.Calls = append(comparison.Calls, ToolCallDiff{
+			Name:   call.name,
+			Status: StatusEqual,
+			Left:   call.raw(),
+			Right:  rightCalls[index].raw(),
+		})
+		rightCalls = slices.Delete(rightCalls, index, index+1)
+	}
+
+	// The different tool calls: the closest tool call of the same name
+	var missing []normalizedToolCall
+	for _, call := range leftRest {
+		best, bestFields := -1, []FieldDiff(nil)
+		for index, other := range rightCalls {
+			if other.name != call.name {
+				continue
+			}
+			fields := diffValues("", call.value, other.value)
+			if best < 0 || len(fields) < len(bestFields) {
+				best, bestFields = index, fields
+			}
+		}
+		if best < 0 {
+			missing = append(missing, call)
+			continue
+		}
+		comparison.Calls = append(comparison.Calls, ToolCallDiff{
+			Name:   call.name,
+			Status: StatusDifferent,
+			Left:   call.raw(),
+			Right:  rightCalls[best].raw(),
+			Fields: bestFields,
+		})
+		rightCalls = slices.Delete(rightCalls, best, best+1)
+	}
+
+	for _, call := range missing {
+		comparison.Calls = append(comparison.Calls, ToolCallDiff{Name: call.name, Status: StatusMissing, Left: call.raw()})
+	}
+	for _, call := range rightCalls {
+		comparison.Calls = append(comparison.Calls, ToolCallDiff{Name: call.name, Status: StatusExtra, Right: call.raw()})
+	}
+
+	for _, call := range comparison.Calls {
+		if call.Status != StatusEqual {
+			comparison.Equal = false
+		}
+	}
+	return comparison
+}
+
+// Count returns the number of compared tool calls with the given status
+func (c Comparison) Count(status string) int {
+	count := 0
+	for _, call := range c.Calls {
+		if call.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// String returns the text report of the comparison, one line per tool call
+func (c Comparison) String() string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "%s vs %s: %d equal, %d different, %d missing, %d extra",
+		c.Left, c.Right, c.Count(StatusEqual), c.Count(StatusDifferent), c.Count(StatusMissing), c.Count(StatusExtra))
+
+	for _, call := range c.Calls {
+		switch call.Status {
+		case StatusEqual:
+			fmt.Fprintf(&report, "\n✅ %s %s", call.Name, call.Left)
+		case StatusDifferent:
+			fmt.Fprintf(&report, "\n😠 %s %s vs %s", call.Name, call.Left, call.Right)
+			for _, field := range call.Fields {
+				fmt.Fprintf(&report, "\n   - %s", field)
+			}
+		case StatusMissing:
+			fmt.Fprintf(&report, "\n❌ %s %s missing in %s", call.Name, call.Left, c.Right)
+		case StatusExtra:
+			fmt.Fprintf(&report, "\n➕ %s %s extra in %s", call.Name, call.Right, c.Right)
+		}
+	}
+	return report.String()
+}
+
+// JSON returns the JSON report of the comparison
+func (c Comparison) JSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// Fprint writes the report of the comparison to w: the JSON report with the "json" format,
+// otherwise the text report and its conclusion
+func (c Comparison) Fprint(w io.Writer, format string) error {
+	if format == "json" {
+		report, err := c.JSON()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(report))
+		return err
+	}
+	conclusion := "😠 Tool calls are not equal"
+	if c.Equal {
+		conclusion = fmt.Sprint("✅ Tool calls are equal: ", len(c.Calls))
+	}
+	_, err := fmt.Fprintf(w, "%s\n%s\n", c, conclusion)
+	return err
+}
+
+func (f FieldDiff) String() string {
+	field := f.Field
+	if field == "" {
+		field = "arguments"
+	}
+	switch f.Status {
+	case StatusMissing:
+		return fmt.Sprintf("%s: missing (%s)", field, f.Left)
+	case StatusExtra:
+		return fmt.Sprintf("%s: extra (%s)", field, f.Right)
+	default:
+		return fmt.Sprintf("%s: %s vs %s", field, f.Left, f.Right)
+	}
+}
+
+// normalizedToolCall is a tool call with its normalized arguments
+type normalizedToolCall struct {
+	name string
+	// value is the decoded arguments (the raw string when they are not valid JSON)
+	value any
+	// arguments is the canonical JSON of the arguments
+	arguments string
+}
+
+func (c normalizedToolCall) raw() json.RawMessage {
+	return json.RawMessage(c.arguments)
+}
+
+func normalizeToolCalls(toolCalls []openai.ChatCompletionMessageToolCall) []normalizedToolCall {
+	calls := make([]normalizedToolCall, 0, len(toolCalls))
+	for _, toolCall := range toolCalls {
+		value := normalizeArguments(toolCall.Function.Arguments)
+		calls = append(calls, normalizedToolCall{
+			name:      toolCall.Function.Name,
+			value:     value,
+			arguments: string(canonicalJSON(value)),
+		})
+	}
+	return calls
+}
+
+// normalizeArguments decodes the JSON arguments of a tool call
+// (the numbers are decoded as float64, so 10 and 10.0 are the same);
+// invalid JSON is kept as a string
+func normalizeArguments(arguments string) any {
+	arguments = strings.TrimSpace(arguments)
+	if arguments == "" {
+		return map[string]any{}
+	}
+	var value any
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return arguments
+	}
+	return value
+}
+
+// canonicalJSON returns the JSON of a decoded value, with sorted keys and without whitespace
+func canonicalJSON(value any) []byte {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return []byte(fmt.Sprintf("%q", fmt.Sprint(value)))
+	}
+	return bytes.TrimSpace(buffer.Bytes())
+}
+
+// diffValues returns the differing fields of two decoded values, recursively in the objects
+func diffValues(path string, left, right any) []FieldDiff {
+	leftObject, leftIsObject := left.(map[string]any)
+	rightObject, rightIsObject := right.(map[string]any)
+	if !leftIsObject || !rightIsObject {
+		if bytes.Equal(canonicalJSON(left), canonicalJSON(right)) {
+			return nil
+		}
+		return []FieldDiff{{Field: path, Status: StatusDifferent, Left: canonicalJSON(left), Right: canonicalJSON(right)}}
+	}
+
+	var fields []FieldDiff
+	keys := slices.Collect(maps.Keys(leftObject))
+	for key := range rightObject {
+		if _, ok := leftObject[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		leftValue, inLeft := leftObject[key]
+		rightValue, inRight := rightObject[key]
+		switch {
+		case !inRight:
+			fields = append(fields, FieldDiff{Field: field, Status: StatusMissing, Left: canonicalJSON(leftValue)})
+		case !inLeft:
+			fields = append(fields, FieldDiff{Field: field, Status: StatusExtra, Right: canonicalJSON(rightValue)})
+		default:
+			fields = append(fields, diffValues(field, leftValue, rightValue)...)
+		}
+	}
+	return fields
+}
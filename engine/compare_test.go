@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func toolCall(name, arguments string) openai.ChatCompletionMessageToolCall {
+	return openai.ChatCompletionMessageToolCall{
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: name, Arguments: arguments},
+	}
+}
+
+func TestCompareToolCalls(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  []openai.ChatCompletionMessageToolCall
+		right []openai.ChatCompletionMessageToolCall
+		// calls are the "status name" of the compared tool calls, fields the differing arguments
+		calls  []string
+		fields []string
+	}{
+		{
+			name:  "number formats",
+			left:  []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"quantity":10}`)},
+			right: []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"quantity":10.0}`)},
+			calls: []string{"equal add_to_cart"},
+		},
+		{
+			name:  "key order and whitespace",
+			left:  []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"product_name":"Dune","quantity":2}`)},
+			right: []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", "{ \"quantity\": 2,\n \"product_name\": \"Dune\" }")},
+			calls: []string{"equal add_to_cart"},
+		},
+		{
+			name:  "empty arguments",
+			left:  []openai.ChatCompletionMessageToolCall{toolCall("view_cart", ``)},
+			right: []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`)},
+			calls: []string{"equal view_cart"},
+		},
+		{
+			name:  "reordered calls",
+			left:  []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`), toolCall("checkout", `{}`)},
+			right: []openai.ChatCompletionMessageToolCall{toolCall("checkout", `{}`), toolCall("view_cart", `{}`)},
+			calls: []string{"equal view_cart", "equal checkout"},
+		},
+		{
+			name:   "different value",
+			left:   []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"product_name":"Dune","quantity":2}`)},
+			right:  []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"product_name":"Dune","quantity":3}`)},
+			calls:  []string{"different add_to_cart"},
+			fields: []string{"quantity: 2 vs 3"},
+		},
+		{
+			name:   "missing, extra and nested fields",
+			left:   []openai.ChatCompletionMessageToolCall{toolCall("search", `{"query":"Dune","filter":{"max":20},"limit":5}`)},
+			right:  []openai.ChatCompletionMessageToolCall{toolCall("search", `{"query":"Dune","filter":{"max":25},"sort":"name"}`)},
+			calls:  []string{"different search"},
+			fields: []string{"filter.max: 20 vs 25", "limit: missing (5)", `sort: extra ("name")`},
+		},
+		{
+			// The different call is paired with the closest call of the same name
+			name: "closest call",
+			left: []openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"product_name":"Dune","quantity":2}`)},
+			right: []openai.ChatCompletionMessageToolCall{
+				toolCall("add_to_cart", `{"product_name":"Sapiens","quantity":5}`),
+				toolCall("add_to_cart", `{"product_name":"Dune","quantity":3}`),
+			},
+			calls:  []string{"different add_to_cart", "extra add_to_cart"},
+			fields: []string{"quantity: 2 vs 3"},
+		},
+		{
+			name:  "missing and extra calls",
+			left:  []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`), toolCall("checkout", `{}`)},
+			right: []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`), toolCall("clear_cart", `{}`)},
+			calls: []string{"equal view_cart", "missing checkout", "extra clear_cart"},
+		},
+		{
+			name:   "invalid JSON",
+			left:   []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{"a":`)},
+			right:  []openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`)},
+			calls:  []string{"different view_cart"},
+			fields: []string{`arguments: "{\"a\":" vs {}`},
+		},
+		{name: "no calls"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comparison := CompareToolCalls("left", test.left, "right", test.right)
+			calls, fields := []string{}, []string{}
+			for _, call := range comparison.Calls {
+				calls = append(calls, call.Status+" "+call.Name)
+				for _, field := range call.Fields {
+					fields = append(fields, field.String())
+				}
+			}
+			if strings.Join(calls, "\n") != strings.Join(test.calls, "\n") {
+				t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(test.calls, "\n"))
+			}
+			if strings.Join(fields, "\n") != strings.Join(test.fields, "\n") {
+				t.Errorf("fields:\n%s\nwant:\n%s", strings.Join(fields, "\n"), strings.Join(test.fields, "\n"))
+			}
+			equal := comparison.Count(StatusEqual) == len(comparison.Calls)
+			if comparison.Equal != equal {
+				t.Errorf("equal %v, want %v", comparison.Equal, equal)
+			}
+		})
+	}
+}
+
+func TestComparisonFprint(t *testing.T) {
+	comparison := CompareToolCalls("🐳 dmr",
+		[]openai.ChatCompletionMessageToolCall{toolCall("view_cart", `{}`), toolCall("add_to_cart", `{"product_name":"Dune","quantity":2}`)},
+		"🦙 ollama",
+		[]openai.ChatCompletionMessageToolCall{toolCall("add_to_cart", `{"quantity":3,"product_name":"Dune"}`), toolCall("view_cart", `{}`), toolCall("checkout", `{}`)},
+	)
+
+	var text bytes.Buffer
+	if err := comparison.Fprint(&text, "text"); err != nil {
+		t.Fatal(err)
+	}
+	want := `🐳 dmr vs 🦙 ollama: 1 equal, 1 different, 0 missing, 1 extra
+✅ view_cart {}
+😠 add_to_cart {"product_name":"Dune","quantity":2} vs {"product_name":"Dune","quantity":3}
+   - quantity: 2 vs 3
+➕ checkout {} extra in 🦙 ollama
+😠 Tool calls are not equal
+`
+	if text.String() != want {
+		t.Errorf("text report:\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	var report bytes.Buffer
+	if err := comparison.Fprint(&report, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Comparison
+	if err := json.Unmarshal(report.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON report: %v\n%s", err, report.String())
+	}
+	if decoded.Left != "🐳 dmr" || decoded.Equal || len(decoded.Calls) != 3 || decoded.Calls[1].Fields[0].Field != "quantity" {
+		t.Errorf("JSON report %+v", decoded)
+	}
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEmbeddings is returned when the server does not return an embedding per input
+var ErrInvalidEmbeddings = errors.New("invalid embeddings")
+
+// Embed returns the embeddings of the texts with the model of the engine (an embedding model,
+// e.g. ai/mxbai-embed-large), through the /embeddings endpoint of the provider
+// (/api/embed for the native Ollama API), in the order of the texts
+func (e *Engine) Embed(texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if err := e.checkModel(); err != nil {
+		return nil, err
+	}
+
+	var embeddings [][]float64
+	err := e.withRetry(func(ctx context.Context) (bool, error) {
+		var err error
+		embeddings, err = e.backend.embed(ctx, e.model, texts)
+		return true, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating embeddings: %w", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("%w: %d embeddings for %d texts", ErrInvalidEmbeddings, len(embeddings), len(texts))
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("%w: no embedding for the text %d", ErrInvalidEmbeddings, i)
+		}
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,234 @@
+// Package engine is the shared tool-calling engine used by every experiment
+// of this repository. It wraps an OpenAI-compatible client (Docker Model
+// Runner, Ollama, ...) or the native Ollama API, and is configured with
+// functional options.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+type Engine struct {
+	ctx      context.Context
+	provider Provider
+	backend  backend
+	model    string
+	tools    []openai.ChatCompletionToolParam
+	// maxTurns is the maximum number of tool completions of Run
+	maxTurns int
+	// options are the default completion options of the engine
+	options []CompletionOption
+	// modelCheck checks the model before the first completion (optional)
+	modelCheck *modelCheck
+	// retryPolicy is the retry policy of the completions
+	retryPolicy RetryPolicy
+	// Params holds the parameters of the last completion request
+	Params openai.ChatCompletionNewParams
+}
+
+// Tools sets the tools catalog sent with every tool completion
+func (e *Engine) Tools(tools []openai.ChatCompletionToolParam) {
+	e.tools = tools
+}
+
+// Provider returns the provider of the engine
+func (e *Engine) Provider() Provider {
+	return e.provider
+}
+
+// capabilities returns the capabilities of the provider (all of them without provider)
+func (e *Engine) capabilities() Capabilities {
+	if e.provider == nil {
+		return Capabilities{ParallelToolCalls: true, StreamUsage: true}
+	}
+	return e.provider.Capabilities()
+}
+
+// Model returns the name of the model used by the engine
+func (e *Engine) Model() string {
+	return e.model
+}
+
+// String returns the provider and the model of the engine, e.g. "Docker Model Runner ai/qwen2.5:0.5B-F16"
+func (e *Engine) String() string {
+	if e.provider == nil {
+		return e.model
+	}
+	return e.provider.Name() + " " + e.model
+}
+
+// ToolCompletion runs a completion with the tools catalog and returns the detected tool calls
+func (e *Engine) ToolCompletion(messages []openai.ChatCompletionMessageParamUnion, options ...CompletionOption) ([]openai.ChatCompletionMessageToolCall, error) {
+	result, err := e.CompleteWithTools(messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	return result.ToolCalls, nil
+}
+
+// CompleteWithTools runs a completion with the tools catalog and returns the whole result:
+// the tool calls, but also the text content when the model answers in prose.
+// It returns ErrNoChoices when the server returns no choices,
+// and ErrTruncated or ErrContentFiltered (with the partial result) depending on the finish reason
+func (e *Engine) CompleteWithTools(messages []openai.ChatCompletionMessageParamUnion, options ...CompletionOption) (CompletionResult, error) {
+	if err := e.checkModel(); err != nil {
+		return CompletionResult{}, err
+	}
+
+	params := e.toolParams(messages, options)
+
+	var completion *openai.ChatCompletion
+	err := e.withRetry(func(ctx context.Context) (bool, error) {
+		var err error
+		completion, err = e.backend.complete(ctx, params)
+		return true, err
+	})
+	if err != nil {
+		return CompletionResult{}, fmt.Errorf("error creating tool completion: %w", err)
+	}
+	return newCompletionResult(completion)
+}
+
+// toolParams returns the parameters of a tool completion and keeps them in e.Params:
+// parallel tool calls (if the provider needs them) and temperature 0 unless overridden by the options
+// (no seed without WithSeed: the server samples with its own default)
+func (e *Engine) toolParams(messages []openai.ChatCompletionMessageParamUnion, options []CompletionOption) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Messages:    messages,
+		Model:       e.model,
+		Tools:       e.tools,
+		Temperature: openai.Opt(0.0),
+	}
+	// Enable parallel tool calls for DMR, no need for this with Ollama
+	if e.capabilities().ParallelToolCalls {
+		params.ParallelToolCalls = openai.Bool(true)
+	}
+	e.applyOptions(&params, options)
+
+	e.Params = params
+	return params
+}
+
+// StreamResult is the result of a streaming chat completion
+type StreamResult struct {
+	// Content is the whole streamed content
+	Content string
+	// FinishReason is the finish reason of the last chunk ("stop", "length", ...)
+	FinishReason string
+	// Usage is the token usage, nil if the server did not send it
+	Usage *openai.CompletionUsage
+}
+
+// ChatStreamCompletion runs a classic chat completion and streams the content to cbk.
+// It returns the error of the stream (a dropped connection, a server error, a cancelled context),
+// the finish reason and the token usage when the server sends it.
+// The options override the temperature and the engine defaults.
+// Use WithContext to cancel a completion
+func (e *Engine) ChatStreamCompletion(messages []openai.ChatCompletionMessageParamUnion, temperature float64, cbk func(content string), options ...CompletionOption) (StreamResult, error) {
+	if err := e.checkModel(); err != nil {
+		return StreamResult{}, err
+	}
+	params := openai.ChatCompletionNewParams{
+		Messages:    messages,
+		Model:       e.model,
+		Temperature: openai.Opt(temperature),
+	}
+	if e.capabilities().StreamUsage {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
+	}
+	e.applyOptions(&params, options)
+
+	e.Params = params
+
+	var result StreamResult
+	var content strings.Builder
+	err := e.stream(params, func(chunk openai.ChatCompletionChunk) {
+		// The last chunk carries the usage (without choices)
+		if chunk.Usage.TotalTokens > 0 {
+			usage := chunk.Usage
+			result.Usage = &usage
+		}
+		if len(chunk.Choices) == 0 {
+			return
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			result.FinishReason = chunk.Choices[0].FinishReason
+		}
+		// Stream each chunk as it arrives
+		if chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			cbk(chunk.Choices[0].Delta.Content)
+		}
+	})
+	result.Content = content.String()
+
+	if err != nil {
+		return result, fmt.Errorf("error streaming chat completion: %w", err)
+	}
+	if err := e.ctx.Err(); err != nil {
+		return result, fmt.Errorf("chat completion cancelled: %w", err)
+	}
+	return result, nil
+}
+
+// stream runs a streaming completion with the retry policy:
+// a failed stream is retried only if no chunk was delivered
+func (e *Engine) stream(params openai.ChatCompletionNewParams, onChunk func(chunk openai.ChatCompletionChunk)) error {
+	return e.withRetry(func(ctx context.Context) (bool, error) {
+		delivered := false
+		err := e.backend.stream(ctx, params, func(chunk openai.ChatCompletionChunk) {
+			delivered = true
+			onChunk(chunk)
+		})
+		return !delivered, err
+	})
+}
+
+// WithContext returns a shallow copy of the engine using ctx for its requests,
+// to cancel them or set a deadline
+func (e *Engine) WithContext(ctx context.Context) *Engine {
+	engine := *e
+	engine.ctx = ctx
+	return &engine
+}
+
+type EngineOption func(*Engine)
+
+// NewEngine creates an engine and applies all the options.
+// Without provider option (WithProvider, WithDockerModelRunner, WithOllama...),
+// the engine uses the OpenAI API (OPENAI_BASE_URL and OPENAI_API_KEY)
+func NewEngine(options ...EngineOption) *Engine {
+	engine := &Engine{
+		ctx:         context.Background(),
+		maxTurns:    DefaultMaxTurns,
+		retryPolicy: DefaultRetryPolicy(),
+	}
+	// Apply all options
+	for _, option := range options {
+		option(engine)
+	}
+	if engine.backend == nil {
+		engine.backend = openAIBackend{client: getDefaultOpenAIClient()}
+	}
+	return engine
+}
+
+// WithModel sets the model used for the completions
+func WithModel(model string) EngineOption {
+	return func(engine *Engine) {
+		engine.model = model
+	}
+}
+
+// WithMaxTurns sets the maximum number of tool completions run by Run
+func WithMaxTurns(maxTurns int) EngineOption {
+	return func(engine *Engine) {
+		engine.maxTurns = maxTurns
+	}
+}
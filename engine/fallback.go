@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+var (
+	// ErrNoToolCalls is the reason of a fallback when an engine answered without tool calls
+	ErrNoToolCalls = errors.New("no tool calls")
+	// ErrAllEnginesFailed is returned by a FallbackEngine when none of its engines answered
+	ErrAllEnginesFailed = errors.New("all the engines failed")
+)
+
+// FallbackEngine is a chain of engines: it tries the first engine (the primary provider and model),
+// then falls back to the next one on errors (transport errors, timeouts, ...)
+// or, for the tool completions, when the engine answered without tool calls.
+// A typical chain is a 0.5B tool model, then a 1.5B one, then Ollama
+type FallbackEngine struct {
+	engines []*Engine
+	// Answered is the engine that answered the last completion
+	Answered *Engine
+	// Fallbacks are the engines skipped during the last completion, with the reason
+	Fallbacks []Fallback
+}
+
+// Fallback is an engine skipped by a FallbackEngine
+type Fallback struct {
+	Engine *Engine
+	// Reason is the error of the engine, or ErrNoToolCalls
+	Reason error
+}
+
+func (f Fallback) String() string {
+	return fmt.Sprintf("%s: %v", f.Engine, f.Reason)
+}
+
+// NewFallbackEngine creates a chain of engines, tried in order
+func NewFallbackEngine(engines ...*Engine) *FallbackEngine {
+	return &FallbackEngine{engines: engines}
+}
+
+// Engines returns the engines of the chain
+func (f *FallbackEngine) Engines() []*Engine {
+	return f.engines
+}
+
+// Tools sets the tools catalog of all the engines
+func (f *FallbackEngine) Tools(tools []openai.ChatCompletionToolParam) {
+	for _, engine := range f.engines {
+		engine.Tools(tools)
+	}
+}
+
+// ToolCompletion runs a tool completion along the chain and returns the detected tool calls
+func (f *FallbackEngine) ToolCompletion(messages []openai.ChatCompletionMessageParamUnion, options ...CompletionOption) ([]openai.ChatCompletionMessageToolCall, error) {
+	result, err := f.CompleteWithTools(messages, options...)
+	return result.ToolCalls, err
+}
+
+// CompleteWithTools runs a tool completion with the first engine,
+// then with the next ones while the engine fails or answers without tool calls.
+// When no engine answered with tool calls but some answered, the answer of the last one is returned
+// with ErrNoToolCalls and the outcome of every engine
+func (f *FallbackEngine) CompleteWithTools(messages []openai.ChatCompletionMessageParamUnion, options ...CompletionOption) (CompletionResult, error) {
+	f.Answered, f.Fallbacks = nil, nil
+
+	var last CompletionResult
+	var lastEngine *Engine
+	for _, engine := range f.engines {
+		result, err := engine.CompleteWithTools(messages, options...)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return result, err
+			}
+			f.Fallbacks = append(f.Fallbacks, Fallback{Engine: engine, Reason: err})
+			continue
+		}
+		if len(result.ToolCalls) > 0 {
+			f.Answered = engine
+			return result, nil
+		}
+		f.Fallbacks = append(f.Fallbacks, Fallback{Engine: engine, Reason: ErrNoToolCalls})
+		last, lastEngine = result, engine
+	}
+
+	if lastEngine != nil {
+		f.Answered = lastEngine
+		return last, f.outcomes(ErrNoToolCalls)
+	}
+	return CompletionResult{}, f.failed()
+}
+
+// ChatStreamCompletion runs a streaming chat completion with the first engine,
+// then with the next ones while the engine fails before streaming any content
+// (a stream interrupted after its first content cannot be replayed)
+func (f *FallbackEngine) ChatStreamCompletion(messages []openai.ChatCompletionMessageParamUnion, temperature float64, cbk func(content string), options ...CompletionOption) (StreamResult, error) {
+	f.Answered, f.Fallbacks = nil, nil
+
+	for _, engine := range f.engines {
+		streamed := false
+		result, err := engine.ChatStreamCompletion(messages, temperature, func(content string) {
+			streamed = true
+			cbk(content)
+		}, options...)
+		if err == nil || streamed || errors.Is(err, context.Canceled) {
+			f.Answered = engine
+			return result, err
+		}
+		f.Fallbacks = append(f.Fallbacks, Fallback{Engine: engine, Reason: err})
+	}
+	return StreamResult{}, f.failed()
+}
+
+// failed returns ErrAllEnginesFailed with the error of every engine
+func (f *FallbackEngine) failed() error {
+	return f.outcomes(ErrAllEnginesFailed)
+}
+
+// outcomes returns an error with the reason of every fallback
+func (f *FallbackEngine) outcomes(err error) error {
+	errs := []error{err}
+	for _, fallback := range f.Fallbacks {
+		errs = append(errs, fmt.Errorf("%s: %w", fallback.Engine, fallback.Reason))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFallbackEngineCompleteWithTools(t *testing.T) {
+	answers := map[string]http.HandlerFunc{
+		"tool call": func(w http.ResponseWriter, r *http.Request) {
+			writeCompletion(w, "tool_calls", "", toolCall("view_cart", `{}`))
+		},
+		"no tool calls": func(w http.ResponseWriter, r *http.Request) {
+			writeCompletion(w, "stop", "I cannot help")
+		},
+		"error": func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusBadRequest, "bad request")
+		},
+	}
+	tests := []struct {
+		name    string
+		engines []string
+		// answered is the index of the engine answering, calls the number of requests of each engine
+		answered  int
+		calls     []int
+		fallbacks int
+		err       error
+	}{
+		{name: "first succeeds", engines: []string{"tool call", "tool call"}, answered: 0, calls: []int{1, 0}},
+		{name: "error then success", engines: []string{"error", "tool call"}, answered: 1, calls: []int{1, 1}, fallbacks: 1},
+		{name: "no tool calls then success", engines: []string{"no tool calls", "tool call"}, answered: 1, calls: []int{1, 1}, fallbacks: 1},
+		{name: "all empty", engines: []string{"no tool calls", "no tool calls"}, answered: 1, calls: []int{1, 1}, fallbacks: 2, err: ErrNoToolCalls},
+		{name: "error then empty", engines: []string{"error", "no tool calls"}, answered: 1, calls: []int{1, 1}, fallbacks: 2, err: ErrNoToolCalls},
+		{name: "all fail", engines: []string{"error", "error"}, answered: -1, calls: []int{1, 1}, fallbacks: 2, err: ErrAllEnginesFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := make([]int, len(test.engines))
+			var engines []*Engine
+			for i, answer := range test.engines {
+				engines = append(engines, testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+					calls[i]++
+					answers[answer](w, r)
+				}))
+			}
+			fallbackEngine := NewFallbackEngine(engines...)
+
+			result, err := fallbackEngine.CompleteWithTools(nil)
+			if (test.err == nil && err != nil) || !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			for i := range calls {
+				if calls[i] != test.calls[i] {
+					t.Errorf("%d requests to the engine %d, want %d", calls[i], i, test.calls[i])
+				}
+			}
+			if len(fallbackEngine.Fallbacks) != test.fallbacks {
+				t.Errorf("fallbacks %v, want %d", fallbackEngine.Fallbacks, test.fallbacks)
+			}
+			switch {
+			case test.answered < 0:
+				if fallbackEngine.Answered != nil {
+					t.Errorf("answered by %s, want none", fallbackEngine.Answered)
+				}
+			case fallbackEngine.Answered != engines[test.answered]:
+				t.Errorf("answered by %v, want the engine %d", fallbackEngine.Answered, test.answered)
+			}
+			if test.err == ErrNoToolCalls && result.Content != "I cannot help" {
+				t.Errorf("content %q, want the answer of the last engine", result.Content)
+			}
+			if test.err == nil && len(result.ToolCalls) != 1 {
+				t.Errorf("tool calls %v, want the view_cart call", result.ToolCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/whales-collective/function-calling/engine
+
+go 1.24.0
+
+require (
+	github.com/openai/openai-go v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/tidwall/gjson v1.14.4 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.1 // indirect
+	github.com/tidwall/sjson v1.2.5 // indirect
+)
@@ -0,0 +1,486 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// OllamaNativeOptions are the settings of the native Ollama API (/api/chat)
+// that the OpenAI-compatible API does not expose
+type OllamaNativeOptions struct {
+	// NumCtx is the size of the context window (options.num_ctx), 0 for the model default
+	NumCtx int
+	// NumPredict is the maximum number of tokens to generate (options.num_predict), 0 for the model default
+	NumPredict int
+	// KeepAlive is how long the model stays loaded after the request (keep_alive, e.g. "5m"), empty for the server default
+	KeepAlive string
+}
+
+// OllamaNative is the Ollama provider, through its native API (/api/chat).
+// The base URL is OLLAMA_BASE_URL without the /v1 suffix of the OpenAI-compatible API
+func OllamaNative() OllamaEndpoint {
+	baseURL := cmp.Or(os.Getenv("OLLAMA_BASE_URL"), "http://localhost:11434/v1")
+	return OllamaEndpoint{Endpoint{
+		ProviderName: "Ollama (native API)",
+		URL:          ollamaRootURL(baseURL),
+		Quirks:       Capabilities{ParallelToolCalls: false, StreamUsage: true},
+	}}
+}
+
+// ollamaRootURL returns the root URL of Ollama, without the /v1 suffix of the OpenAI-compatible API
+func ollamaRootURL(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
+}
+
+// WithOllamaNative uses the native Ollama API (/api/chat) instead of the OpenAI-compatible one
+func WithOllamaNative(ctx context.Context, options OllamaNativeOptions) EngineOption {
+	return func(engine *Engine) {
+		provider := OllamaNative()
+		engine.ctx = ctx
+		engine.provider = provider
+		engine.backend = ollamaBackend{
+			baseURL:    provider.BaseURL(),
+			options:    options,
+			httpClient: http.DefaultClient,
+		}
+	}
+}
+
+// ollamaBackend talks to the native Ollama API and converts
+// the OpenAI requests and responses from and to the Ollama format
+type ollamaBackend struct {
+	baseURL    string
+	options    OllamaNativeOptions
+	httpClient *http.Client
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Tools     json.RawMessage `json:"tools,omitempty"`
+	Stream    bool            `json:"stream"`
+	Options   map[string]any  `json:"options,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// openAIMessage is the JSON form of the OpenAI messages sent by the engine
+type openAIMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolCalls  []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// openAIRequest is the JSON form of the OpenAI parameters used by the engine
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       json.RawMessage `json:"tools"`
+	Temperature *float64        `json:"temperature"`
+	TopP        *float64        `json:"top_p"`
+	Seed        *int64          `json:"seed"`
+	MaxTokens   *int64          `json:"max_tokens"`
+	Stop        json.RawMessage `json:"stop"`
+}
+
+// request converts the OpenAI parameters to an Ollama chat request
+func (b ollamaBackend) request(params openai.ChatCompletionNewParams, stream bool) (ollamaChatRequest, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ollamaChatRequest{}, err
+	}
+	var openAIParams openAIRequest
+	if err := json.Unmarshal(data, &openAIParams); err != nil {
+		return ollamaChatRequest{}, err
+	}
+
+	request := ollamaChatRequest{
+		Model:     openAIParams.Model,
+		Tools:     openAIParams.Tools,
+		Stream:    stream,
+		Options:   map[string]any{},
+		KeepAlive: b.options.KeepAlive,
+	}
+
+	// The tool messages of Ollama carry the name of the tool instead of the id of the call
+	toolNames := map[string]string{}
+	for _, message := range openAIParams.Messages {
+		ollamaMessage := ollamaMessage{
+			Role:    message.Role,
+			Content: messageText(message.Content),
+		}
+		for _, toolCall := range message.ToolCalls {
+			toolNames[toolCall.ID] = toolCall.Function.Name
+			call := ollamaToolCall{}
+			call.Function.Name = toolCall.Function.Name
+			call.Function.Arguments = json.RawMessage(cmp.Or(toolCall.Function.Arguments, "{}"))
+			if !json.Valid(call.Function.Arguments) {
+				call.Function.Arguments = json.RawMessage("{}")
+			}
+			ollamaMessage.ToolCalls = append(ollamaMessage.ToolCalls, call)
+		}
+		if message.Role == "tool" {
+			ollamaMessage.ToolName = toolNames[message.ToolCallID]
+		}
+		request.Messages = append(request.Messages, ollamaMessage)
+	}
+
+	if b.options.NumCtx > 0 {
+		request.Options["num_ctx"] = b.options.NumCtx
+	}
+	if b.options.NumPredict > 0 {
+		request.Options["num_predict"] = b.options.NumPredict
+	}
+	// The completion options of the call override the native options
+	if openAIParams.Temperature != nil {
+		request.Options["temperature"] = *openAIParams.Temperature
+	}
+	if openAIParams.TopP != nil {
+		request.Options["top_p"] = *openAIParams.TopP
+	}
+	if openAIParams.Seed != nil {
+		request.Options["seed"] = *openAIParams.Seed
+	}
+	if openAIParams.MaxTokens != nil {
+		request.Options["num_predict"] = *openAIParams.MaxTokens
+	}
+	if len(openAIParams.Stop) > 0 {
+		var stop []string
+		if err := json.Unmarshal(openAIParams.Stop, &stop); err != nil {
+			var single string
+			if json.Unmarshal(openAIParams.Stop, &single) == nil {
+				stop = []string{single}
+			}
+		}
+		request.Options["stop"] = stop
+	}
+	return request, nil
+}
+
+// messageText returns the text of an OpenAI message content (a string or an array of text parts)
+func messageText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(content, &parts) == nil {
+		texts := []string{}
+		for _, part := range parts {
+			texts = append(texts, part.Text)
+		}
+		return strings.Join(texts, "")
+	}
+	return ""
+}
+
+func (b ollamaBackend) post(ctx context.Context, request ollamaChatRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	response, err := b.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		message, _ := io.ReadAll(response.Body)
+		return nil, &StatusError{
+			Method:     http.MethodPost,
+			URL:        httpRequest.URL.String(),
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       strings.TrimSpace(string(message)),
+		}
+	}
+	return response, nil
+}
+
+func (b ollamaBackend) complete(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	request, err := b.request(params, false)
+	if err != nil {
+		return nil, err
+	}
+	response, err := b.post(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var chatResponse ollamaChatResponse
+	if err := json.NewDecoder(response.Body).Decode(&chatResponse); err != nil {
+		return nil, fmt.Errorf("error decoding Ollama response: %w", err)
+	}
+	if chatResponse.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chatResponse.Error)
+	}
+
+	message := map[string]any{
+		"role":    "assistant",
+		"content": chatResponse.Message.Content,
+	}
+	if toolCalls := openAIToolCalls(chatResponse.Message.ToolCalls, false); len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+	completion := map[string]any{
+		"id":      "ollama-" + chatResponse.CreatedAt.Format(time.RFC3339Nano),
+		"object":  "chat.completion",
+		"created": chatResponse.CreatedAt.Unix(),
+		"model":   chatResponse.Model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason(chatResponse),
+		}},
+		"usage": openAIUsage(chatResponse),
+	}
+	return convert[openai.ChatCompletion](completion)
+}
+
+func (b ollamaBackend) stream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(chunk openai.ChatCompletionChunk)) error {
+	request, err := b.request(params, true)
+	if err != nil {
+		return err
+	}
+	response, err := b.post(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Ollama streams one JSON object per line
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	toolCallIndex := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chatResponse ollamaChatResponse
+		if err := json.Unmarshal(line, &chatResponse); err != nil {
+			return fmt.Errorf("error decoding Ollama response: %w", err)
+		}
+		if chatResponse.Error != "" {
+			return fmt.Errorf("ollama error: %s", chatResponse.Error)
+		}
+
+		delta := map[string]any{
+			"role":    "assistant",
+			"content": chatResponse.Message.Content,
+		}
+		// Ollama sends complete tool calls, they are indexed across the chunks
+		if toolCalls := openAIToolCalls(chatResponse.Message.ToolCalls, true); len(toolCalls) > 0 {
+			for _, toolCall := range toolCalls {
+				toolCall["index"] = toolCallIndex
+				toolCall["id"] = fmt.Sprintf("call_%d", toolCallIndex)
+				toolCallIndex++
+			}
+			delta["tool_calls"] = toolCalls
+		}
+		choice := map[string]any{
+			"index": 0,
+			"delta": delta,
+		}
+		if chatResponse.Done {
+			choice["finish_reason"] = chatResponse.DoneReason
+			if toolCallIndex > 0 {
+				choice["finish_reason"] = "tool_calls"
+			}
+		}
+		chunk := map[string]any{
+			"id":      "ollama-" + chatResponse.CreatedAt.Format(time.RFC3339Nano),
+			"object":  "chat.completion.chunk",
+			"created": chatResponse.CreatedAt.Unix(),
+			"model":   chatResponse.Model,
+			"choices": []any{choice},
+		}
+		if chatResponse.Done {
+			chunk["usage"] = openAIUsage(chatResponse)
+		}
+
+		openAIChunk, err := convert[openai.ChatCompletionChunk](chunk)
+		if err != nil {
+			return err
+		}
+		onChunk(*openAIChunk)
+	}
+	return scanner.Err()
+}
+
+func (b ollamaBackend) listModels(ctx context.Context) ([]string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := b.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			Method:     http.MethodGet,
+			URL:        request.URL.String(),
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+		}
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("error decoding Ollama tags: %w", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
+func (b ollamaBackend) embed(ctx context.Context, model string, inputs []string) ([][]float64, error) {
+	body, err := json.Marshal(struct {
+		Model     string   `json:"model"`
+		Input     []string `json:"input"`
+		KeepAlive string   `json:"keep_alive,omitempty"`
+	}{model, inputs, b.options.KeepAlive})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := b.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(response.Body)
+		return nil, &StatusError{
+			Method:     http.MethodPost,
+			URL:        request.URL.String(),
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       strings.TrimSpace(string(message)),
+		}
+	}
+
+	var embed struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&embed); err != nil {
+		return nil, fmt.Errorf("error decoding Ollama embeddings: %w", err)
+	}
+	return embed.Embeddings, nil
+}
+
+// openAIToolCalls converts the Ollama tool calls (with JSON object arguments)
+// to OpenAI tool calls (with JSON string arguments)
+func openAIToolCalls(toolCalls []ollamaToolCall, streaming bool) []map[string]any {
+	calls := []map[string]any{}
+	for i, toolCall := range toolCalls {
+		arguments := string(toolCall.Function.Arguments)
+		if arguments == "" || arguments == "null" {
+			arguments = "{}"
+		}
+		call := map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":      toolCall.Function.Name,
+				"arguments": arguments,
+			},
+		}
+		if !streaming {
+			call["id"] = fmt.Sprintf("call_%d", i)
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func finishReason(response ollamaChatResponse) string {
+	if len(response.Message.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return cmp.Or(response.DoneReason, "stop")
+}
+
+func openAIUsage(response ollamaChatResponse) map[string]any {
+	return map[string]any{
+		"prompt_tokens":     response.PromptEvalCount,
+		"completion_tokens": response.EvalCount,
+		"total_tokens":      response.PromptEvalCount + response.EvalCount,
+	}
+}
+
+// convert converts a JSON-like value to an OpenAI response type,
+// through JSON to fill its metadata
+func convert[T any](value any) (*T, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestOllamaBackendRequest(t *testing.T) {
+	assistant := openai.ChatCompletionAssistantMessageParam{
+		ToolCalls: []openai.ChatCompletionMessageToolCallParam{
+			{ID: "call_0", Function: openai.ChatCompletionMessageToolCallFunctionParam{Name: "add_to_cart", Arguments: `{"product_name":"Dune","quantity":2}`}},
+			{ID: "call_1", Function: openai.ChatCompletionMessageToolCallFunctionParam{Name: "view_cart", Arguments: ``}},
+		},
+	}
+	params := openai.ChatCompletionNewParams{
+		Model: "qwen2.5:1.5b",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("You are a shop assistant"),
+			openai.UserMessage("add 2 Dune and show the cart"),
+			{OfAssistant: &assistant},
+			openai.ToolMessage("Added 2 of 'Dune'", "call_0"),
+			openai.ToolMessage("Cart: 2 Dune", "call_1"),
+		},
+	}
+	for _, option := range []CompletionOption{WithTemperature(0.5), WithStop("###"), WithSeed(7), WithMaxTokens(100)} {
+		option(&params)
+	}
+	backend := ollamaBackend{options: OllamaNativeOptions{NumCtx: 4096, NumPredict: 50, KeepAlive: "5m"}}
+
+	request, err := backend.request(params, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Model != "qwen2.5:1.5b" || request.Stream || request.KeepAlive != "5m" {
+		t.Errorf("request %+v", request)
+	}
+	type message struct{ role, content, toolName, toolCalls string }
+	var messages []message
+	for _, ollamaMessage := range request.Messages {
+		toolCalls, _ := json.Marshal(ollamaMessage.ToolCalls)
+		if len(ollamaMessage.ToolCalls) == 0 {
+			toolCalls = nil
+		}
+		messages = append(messages, message{ollamaMessage.Role, ollamaMessage.Content, ollamaMessage.ToolName, string(toolCalls)})
+	}
+	want := []message{
+		{role: "system", content: "You are a shop assistant"},
+		{role: "user", content: "add 2 Dune and show the cart"},
+		// The arguments are JSON objects, not strings
+		{role: "assistant", toolCalls: `[{"function":{"name":"add_to_cart","arguments":{"product_name":"Dune","quantity":2}}},{"function":{"name":"view_cart","arguments":{}}}]`},
+		// The tool messages carry the name of the tool instead of the id of the call
+		{role: "tool", content: "Added 2 of 'Dune'", toolName: "add_to_cart"},
+		{role: "tool", content: "Cart: 2 Dune", toolName: "view_cart"},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages:\n%+v\nwant:\n%+v", messages, want)
+	}
+
+	// The completion options override the native options (num_predict)
+	options, _ := json.Marshal(request.Options)
+	if string(options) != `{"num_ctx":4096,"num_predict":100,"seed":7,"stop":["###"],"temperature":0.5}` {
+		t.Errorf("options %s", options)
+	}
+}
+
+func TestOllamaBackendComplete(t *testing.T) {
+	var request ollamaChatRequest
+	server := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &request)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"qwen2.5:1.5b","created_at":"2025-06-01T10:00:00Z","done":true,"done_reason":"stop",
+			"message":{"role":"assistant","content":"","tool_calls":[
+				{"function":{"name":"add_to_cart","arguments":{"product_name":"Dune","quantity":2}}},
+				{"function":{"name":"view_cart","arguments":{}}}]},
+			"prompt_eval_count":30,"eval_count":12}`)
+	}
+	ollama := httptest.NewServer(http.HandlerFunc(server))
+	defer ollama.Close()
+	t.Setenv("OLLAMA_BASE_URL", ollama.URL+"/v1")
+	toolEngine := NewEngine(WithOllamaNative(context.Background(), OllamaNativeOptions{}), WithModel("qwen2.5:1.5b"))
+
+	result, err := toolEngine.CompleteWithTools([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("add 2 Dune")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Stream || len(request.Messages) != 1 || request.Options["temperature"] != 0.0 {
+		t.Errorf("request %+v", request)
+	}
+	if result.FinishReason != "tool_calls" || result.Usage.PromptTokens != 30 || result.Usage.CompletionTokens != 12 {
+		t.Errorf("finish reason %q, usage %+v", result.FinishReason, result.Usage)
+	}
+	var calls []string
+	for _, toolCall := range result.ToolCalls {
+		calls = append(calls, toolCall.ID+" "+toolCall.Function.Name+" "+toolCall.Function.Arguments)
+	}
+	want := []string{`call_0 add_to_cart {"product_name":"Dune","quantity":2}`, "call_1 view_cart {}"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("tool calls %q, want %q", calls, want)
+	}
+}
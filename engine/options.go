@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"github.com/openai/openai-go"
+)
+
+// CompletionOption overrides a parameter of a completion request.
+// The options are applied on top of the engine defaults (see WithCompletionOptions)
+type CompletionOption func(params *openai.ChatCompletionNewParams)
+
+// WithCompletionOptions sets the default completion options of the engine,
+// applied to every request before the options of the call
+func WithCompletionOptions(options ...CompletionOption) EngineOption {
+	return func(engine *Engine) {
+		engine.options = append(engine.options, options...)
+	}
+}
+
+// WithTemperature sets the sampling temperature
+func WithTemperature(temperature float64) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.Temperature = openai.Float(temperature)
+	}
+}
+
+// WithTopP sets the nucleus sampling probability mass (top_p)
+func WithTopP(topP float64) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.TopP = openai.Float(topP)
+	}
+}
+
+// WithMaxTokens sets the maximum number of tokens to generate (max_tokens)
+func WithMaxTokens(maxTokens int64) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.MaxTokens = openai.Int(maxTokens)
+	}
+}
+
+// WithStop sets the sequences where the model stops generating
+func WithStop(stop ...string) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: stop}
+	}
+}
+
+// WithSeed sets the seed of the sampling
+func WithSeed(seed int64) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.Seed = openai.Int(seed)
+	}
+}
+
+// WithToolChoice controls which tool is called: "auto", "none", "required"
+// or the name of a function to force a call to this function
+func WithToolChoice(choice string) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		switch choice {
+		case "auto", "none", "required":
+			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
+				OfAuto: openai.String(choice),
+			}
+		default:
+			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
+				OfChatCompletionNamedToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+					Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: choice},
+				},
+			}
+		}
+	}
+}
+
+// WithParallelToolCalls enables or disables the parallel tool calls
+func WithParallelToolCalls(parallel bool) CompletionOption {
+	return func(params *openai.ChatCompletionNewParams) {
+		params.ParallelToolCalls = openai.Bool(parallel)
+	}
+}
+
+// applyOptions applies the engine defaults then the options of the call
+func (e *Engine) applyOptions(params *openai.ChatCompletionNewParams, options []CompletionOption) {
+	for _, option := range e.options {
+		option(params)
+	}
+	for _, option := range options {
+		option(params)
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+var (
+	// ErrModelNotFound is returned by the probe when the model is not available on the provider
+	ErrModelNotFound = errors.New("model not found")
+	// ErrNoToolSupport is returned by the probe when the model answers without calling tools
+	// or when the server rejects the tools of the request
+	ErrNoToolSupport = errors.New("model does not support tool calls")
+)
+
+// ProbeReport is what the probe found out about the provider and the model
+type ProbeReport struct {
+	Provider string
+	Model    string
+	// Models are the models available on the provider
+	Models []string
+	// ModelFound is true when the model is in Models
+	ModelFound bool
+	// ToolCalls is true when the model called the probe tool
+	ToolCalls bool
+	// ParallelToolCalls is true when the model returned several tool calls in one completion
+	ParallelToolCalls bool
+}
+
+func (r ProbeReport) String() string {
+	check := func(ok bool) string {
+		if ok {
+			return "✅"
+		}
+		return "❌"
+	}
+	return fmt.Sprintf("%s %s: model found %s, tool calls %s, parallel tool calls %s",
+		r.Provider, r.Model, check(r.ModelFound), check(r.ToolCalls), check(r.ParallelToolCalls))
+}
+
+// probeToolArgs are the arguments of the tiny known tool of the probe
+type probeToolArgs struct {
+	Name string `json:"name" description:"The name of the person"`
+}
+
+// NewProbedEngine creates an engine and probes it (see Probe),
+// so a missing model or a model without tools support is reported before the real run
+func NewProbedEngine(options ...EngineOption) (*Engine, ProbeReport, error) {
+	engine := NewEngine(options...)
+	report, err := engine.Probe()
+	return engine, report, err
+}
+
+// Probe checks the provider and the model:
+// it lists the models of the provider (/models), then sends a tiny tool request
+// to check that the model calls tools and can return several tool calls at once.
+// It returns ErrModelNotFound or ErrNoToolSupport with the report, or the error of the requests
+// (a connection error, a server error, a truncated answer...);
+// a model without parallel tool calls is only reported
+func (e *Engine) Probe() (ProbeReport, error) {
+	report := ProbeReport{Model: e.model}
+	if e.provider != nil {
+		report.Provider = e.provider.Name()
+	}
+
+	models, err := e.backend.listModels(e.ctx)
+	if err != nil {
+		return report, fmt.Errorf("error listing the models of %s: %w", report.Provider, err)
+	}
+	report.Models = models
+	report.ModelFound = hasModel(models, e.model)
+	if !report.ModelFound {
+		return report, fmt.Errorf("%w: %s (available models: %s)", ErrModelNotFound, e.model, strings.Join(models, ", "))
+	}
+
+	// Probe with a copy of the engine to keep its tools catalog
+	probe := *e
+	probe.tools = []openai.ChatCompletionToolParam{
+		NewTool("say_hello", "Say hello to the given person name", func(args probeToolArgs) (string, error) {
+			return "Hello " + args.Name, nil
+		}).Param,
+	}
+	result, err := probe.CompleteWithTools(
+		[]openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage("Say hello to Bob.\nSay hello to Alice."),
+		},
+		WithMaxTokens(256),
+	)
+	if err != nil {
+		if rejectsTools(err) {
+			return report, fmt.Errorf("%w: %s: %w", ErrNoToolSupport, e.model, err)
+		}
+		return report, fmt.Errorf("error probing the tool calls of %s: %w", e.model, err)
+	}
+
+	for _, toolCall := range result.ToolCalls {
+		if toolCall.Function.Name == "say_hello" {
+			report.ToolCalls = true
+		}
+	}
+	report.ParallelToolCalls = report.ToolCalls && len(result.ToolCalls) > 1
+	if !report.ToolCalls {
+		return report, fmt.Errorf("%w: %s answered without calling the tool", ErrNoToolSupport, e.model)
+	}
+	return report, nil
+}
+
+// rejectsTools tells if the error of a completion is the server rejecting the tools of the request
+// (a client error whose body mentions the tools, e.g. Ollama: "... does not support tools"),
+// and not a connection, server or truncation error
+func rejectsTools(err error) bool {
+	statusCode, body := 0, ""
+	var statusError *StatusError
+	var apiError *openai.Error
+	switch {
+	case errors.As(err, &statusError):
+		statusCode, body = statusError.StatusCode, statusError.Body
+	case errors.As(err, &apiError):
+		statusCode, body = apiError.StatusCode, apiError.RawJSON()
+	}
+	// The body only: the messages wrapping the error can mention the tools ("error creating tool completion")
+	return statusCode >= 400 && statusCode < 500 && strings.Contains(strings.ToLower(body), "tool")
+}
+
+// hasModel checks if the model is in the list, the ":latest" tag being optional
+func hasModel(models []string, model string) bool {
+	normalize := func(name string) string {
+		name = strings.ToLower(name)
+		if !strings.Contains(name, ":") {
+			name += ":latest"
+		}
+		return name
+	}
+	for _, candidate := range models {
+		if normalize(candidate) == normalize(model) {
+			return true
+		}
+	}
+	return false
+}
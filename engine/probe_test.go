@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// writeModels writes the list of the models of the provider (/models)
+func writeModels(w http.ResponseWriter, models ...string) {
+	data := []any{}
+	for _, model := range models {
+		data = append(data, map[string]any{"id": model, "object": "model", "created": 1, "owned_by": "test"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": data})
+}
+
+func TestProbe(t *testing.T) {
+	hello := func(names ...string) []openai.ChatCompletionMessageToolCall {
+		var toolCalls []openai.ChatCompletionMessageToolCall
+		for _, name := range names {
+			toolCalls = append(toolCalls, toolCall("say_hello", fmt.Sprintf(`{"name":%q}`, name)))
+		}
+		return toolCalls
+	}
+	tests := []struct {
+		name   string
+		models []string
+		// completion answers the tool request of the probe
+		completion func(w http.ResponseWriter)
+		// report is "model found, tool calls, parallel tool calls"
+		report      [3]bool
+		completions int
+		// failed is true when the probe fails, err is the expected error (when it is a known one)
+		failed bool
+		err    error
+	}{
+		{
+			name:        "parallel tool calls",
+			models:      []string{"other", "m"},
+			completion:  func(w http.ResponseWriter) { writeCompletion(w, "tool_calls", "", hello("Bob", "Alice")...) },
+			report:      [3]bool{true, true, true},
+			completions: 1,
+		},
+		{
+			name:        "single tool call",
+			models:      []string{"m:latest"},
+			completion:  func(w http.ResponseWriter) { writeCompletion(w, "tool_calls", "", hello("Bob")...) },
+			report:      [3]bool{true, true, false},
+			completions: 1,
+		},
+		{
+			name:   "model not found",
+			models: []string{"other"},
+			failed: true,
+			err:    ErrModelNotFound,
+		},
+		{
+			name:        "answer without tool calls",
+			models:      []string{"m"},
+			completion:  func(w http.ResponseWriter) { writeCompletion(w, "stop", "Hello Bob and Alice") },
+			report:      [3]bool{true, false, false},
+			completions: 1,
+			failed:      true,
+			err:         ErrNoToolSupport,
+		},
+		{
+			name:   "tools rejected",
+			models: []string{"m"},
+			completion: func(w http.ResponseWriter) {
+				writeError(w, http.StatusBadRequest, "registry.ollama.ai/library/m does not support tools")
+			},
+			report:      [3]bool{true, false, false},
+			completions: 1,
+			failed:      true,
+			err:         ErrNoToolSupport,
+		},
+		{
+			// Another client error is not a missing tools support
+			name:        "bad request",
+			models:      []string{"m"},
+			completion:  func(w http.ResponseWriter) { writeError(w, http.StatusBadRequest, "invalid max_tokens") },
+			report:      [3]bool{true, false, false},
+			completions: 1,
+			failed:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			completions := 0
+			probedEngine := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/models":
+					writeModels(w, test.models...)
+				case "/v1/chat/completions":
+					completions++
+					test.completion(w)
+				default:
+					http.NotFound(w, r)
+				}
+			}, WithRetryPolicy(testRetryPolicy()))
+
+			report, err := probedEngine.Probe()
+			if (err != nil) != test.failed || (test.err != nil && !errors.Is(err, test.err)) {
+				t.Fatalf("error %v, want %v (failed: %v)", err, test.err, test.failed)
+			}
+			if errors.Is(err, ErrNoToolSupport) != (test.err == ErrNoToolSupport) {
+				t.Errorf("error %v, want %v", err, test.err)
+			}
+			got := [3]bool{report.ModelFound, report.ToolCalls, report.ParallelToolCalls}
+			if got != test.report || completions != test.completions {
+				t.Errorf("report %v, completions %d, want %v, %d", got, completions, test.report, test.completions)
+			}
+			if report.Model != "m" || len(report.Models) != len(test.models) {
+				t.Errorf("report %+v", report)
+			}
+		})
+	}
+}
+
+func TestRejectsTools(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "status error", err: fmt.Errorf("completion: %w", &StatusError{StatusCode: http.StatusBadRequest, Body: `{"error":"m does not support tools"}`}), want: true},
+		{name: "other client error", err: &StatusError{StatusCode: http.StatusBadRequest, Body: "invalid max_tokens"}},
+		{name: "tool in the wrapping message", err: fmt.Errorf("error creating tool completion: %w", &StatusError{StatusCode: http.StatusBadRequest, Body: "invalid max_tokens"})},
+		{name: "server error", err: &StatusError{StatusCode: http.StatusInternalServerError, Body: "tool template error"}},
+		{name: "truncated", err: fmt.Errorf("%w: tool call", ErrTruncated)},
+		{name: "connection error", err: errors.New("dial tcp: connection refused")},
+	}
+	for _, test := range tests {
+		if got := rejectsTools(test.err); got != test.want {
+			t.Errorf("%s: rejectsTools(%v) = %v, want %v", test.name, test.err, got, test.want)
+		}
+	}
+}
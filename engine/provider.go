@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"os"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Provider is an OpenAI-compatible backend serving the models
+type Provider interface {
+	// Name is the display name of the provider
+	Name() string
+	// BaseURL is the base URL of the OpenAI-compatible API (ending with /v1/ or so)
+	BaseURL() string
+	// APIKey is the API key sent as a Bearer token (empty if not needed)
+	APIKey() string
+	// Capabilities are the quirks of the provider
+	Capabilities() Capabilities
+}
+
+// Capabilities describe what a provider supports or needs
+type Capabilities struct {
+	// ParallelToolCalls is true when parallel_tool_calls must be sent
+	// to get several tool calls in one completion (DMR), false when the
+	// provider returns them anyway and the parameter is not needed (Ollama)
+	ParallelToolCalls bool
+	// StreamUsage is true when the provider sends the token usage
+	// of a streaming completion (stream_options.include_usage)
+	StreamUsage bool
+}
+
+// Endpoint is a Provider defined by its fields
+type Endpoint struct {
+	ProviderName string
+	URL          string
+	Key          string
+	Quirks       Capabilities
+}
+
+func (e Endpoint) Name() string               { return e.ProviderName }
+func (e Endpoint) BaseURL() string            { return e.URL }
+func (e Endpoint) APIKey() string             { return e.Key }
+func (e Endpoint) Capabilities() Capabilities { return e.Quirks }
+
+// DockerModelRunner is the Docker Model Runner provider (MODEL_RUNNER_BASE_URL)
+func DockerModelRunner() DockerModelRunnerEndpoint {
+	return DockerModelRunnerEndpoint{Endpoint{
+		ProviderName: "Docker Model Runner",
+		URL:          cmp.Or(os.Getenv("MODEL_RUNNER_BASE_URL"), "http://localhost:12434/engines/llama.cpp/v1/"),
+		Quirks:       Capabilities{ParallelToolCalls: true, StreamUsage: true},
+	}}
+}
+
+// Ollama is the Ollama provider, through its OpenAI-compatible API (OLLAMA_BASE_URL)
+func Ollama() OllamaEndpoint {
+	return OllamaEndpoint{Endpoint{
+		ProviderName: "Ollama",
+		URL:          cmp.Or(os.Getenv("OLLAMA_BASE_URL"), "http://localhost:11434/v1"),
+		Quirks:       Capabilities{ParallelToolCalls: false, StreamUsage: true},
+	}}
+}
+
+// LlamaCpp is the llama.cpp server (or a llamafile) provider (LLAMACPP_BASE_URL)
+func LlamaCpp() Endpoint {
+	return Endpoint{
+		ProviderName: "llama.cpp",
+		URL:          cmp.Or(os.Getenv("LLAMACPP_BASE_URL"), "http://127.0.0.1:8080/v1/"),
+		Key:          os.Getenv("LLAMACPP_API_KEY"),
+		Quirks:       Capabilities{ParallelToolCalls: true, StreamUsage: true},
+	}
+}
+
+// VLLM is the vLLM provider (VLLM_BASE_URL, VLLM_API_KEY)
+func VLLM() Endpoint {
+	return Endpoint{
+		ProviderName: "vLLM",
+		URL:          cmp.Or(os.Getenv("VLLM_BASE_URL"), "http://localhost:8000/v1"),
+		Key:          os.Getenv("VLLM_API_KEY"),
+		Quirks:       Capabilities{ParallelToolCalls: true, StreamUsage: true},
+	}
+}
+
+// LMStudio is the LM Studio provider (LMSTUDIO_BASE_URL)
+func LMStudio() Endpoint {
+	return Endpoint{
+		ProviderName: "LM Studio",
+		URL:          cmp.Or(os.Getenv("LMSTUDIO_BASE_URL"), "http://localhost:1234/v1"),
+		Quirks:       Capabilities{ParallelToolCalls: false, StreamUsage: true},
+	}
+}
+
+// OpenAICompatible is a generic OpenAI-compatible provider
+func OpenAICompatible(baseURL, apiKey string) Endpoint {
+	return Endpoint{
+		ProviderName: "OpenAI-compatible",
+		URL:          baseURL,
+		Key:          apiKey,
+		Quirks:       Capabilities{ParallelToolCalls: true, StreamUsage: true},
+	}
+}
+
+func getOpenAIClient(provider Provider) openai.Client {
+	client := openai.NewClient(
+		option.WithBaseURL(provider.BaseURL()),
+		option.WithAPIKey(provider.APIKey()),
+		// The retries are done by the engine (see RetryPolicy)
+		option.WithMaxRetries(0),
+	)
+	return client
+}
+
+// getDefaultOpenAIClient returns the client of the OpenAI API, configured by the OPENAI_* env variables
+func getDefaultOpenAIClient() openai.Client {
+	// The retries are done by the engine (see RetryPolicy)
+	return openai.NewClient(option.WithMaxRetries(0))
+}
+
+// WithProvider uses the given provider for the completions
+func WithProvider(ctx context.Context, provider Provider) EngineOption {
+	return func(engine *Engine) {
+		engine.ctx = ctx
+		engine.provider = provider
+		engine.backend = openAIBackend{client: getOpenAIClient(provider)}
+	}
+}
+
+// WithDockerModelRunner uses the Docker Model Runner endpoint (MODEL_RUNNER_BASE_URL)
+func WithDockerModelRunner(ctx context.Context) EngineOption {
+	return WithProvider(ctx, DockerModelRunner())
+}
+
+// WithOllama uses the Ollama OpenAI-compatible endpoint (OLLAMA_BASE_URL)
+func WithOllama(ctx context.Context) EngineOption {
+	return WithProvider(ctx, Ollama())
+}
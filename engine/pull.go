@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PullProgress reports the progress of a model pull
+type PullProgress struct {
+	Model  string
+	Status string
+	// Completed and Total are the downloaded and total bytes (0 if unknown)
+	Completed int64
+	Total     int64
+}
+
+// Percent returns the progress of the download (0 if unknown)
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) * 100 / float64(p.Total)
+}
+
+// ModelPuller is implemented by the providers that can pull models
+type ModelPuller interface {
+	PullModel(ctx context.Context, model string, onProgress func(progress PullProgress)) error
+}
+
+// DockerModelRunnerEndpoint is the Docker Model Runner provider, it can pull models
+type DockerModelRunnerEndpoint struct {
+	Endpoint
+}
+
+// OllamaEndpoint is the Ollama provider, it can pull models
+type OllamaEndpoint struct {
+	Endpoint
+}
+
+// rootURL returns the root URL of Docker Model Runner,
+// e.g. http://localhost:12434 for http://localhost:12434/engines/llama.cpp/v1/
+func (d DockerModelRunnerEndpoint) rootURL() string {
+	if index := strings.Index(d.URL, "/engines"); index >= 0 {
+		return d.URL[:index]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(d.URL, "/"), "/v1")
+}
+
+// PullModel pulls the model with the Docker Model Runner API (POST /models/create)
+func (d DockerModelRunnerEndpoint) PullModel(ctx context.Context, model string, onProgress func(progress PullProgress)) error {
+	return pullModel(ctx, d.rootURL()+"/models/create", map[string]any{"from": model},
+		func(line []byte) (PullProgress, error) {
+			var message struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+				Total   int64  `json:"total"`
+				Pulled  int64  `json:"pulled"`
+			}
+			if err := json.Unmarshal(line, &message); err != nil {
+				// Not a progress message
+				return PullProgress{Model: model, Status: string(line)}, nil
+			}
+			if message.Type == "error" {
+				return PullProgress{}, errors.New(message.Message)
+			}
+			return PullProgress{Model: model, Status: message.Message, Completed: message.Pulled, Total: message.Total}, nil
+		},
+		onProgress,
+	)
+}
+
+// PullModel pulls the model with the Ollama API (POST /api/pull)
+func (o OllamaEndpoint) PullModel(ctx context.Context, model string, onProgress func(progress PullProgress)) error {
+	return pullModel(ctx, ollamaRootURL(o.URL)+"/api/pull", map[string]any{"model": model, "stream": true},
+		func(line []byte) (PullProgress, error) {
+			var message struct {
+				Status    string `json:"status"`
+				Error     string `json:"error"`
+				Total     int64  `json:"total"`
+				Completed int64  `json:"completed"`
+			}
+			if err := json.Unmarshal(line, &message); err != nil {
+				return PullProgress{}, fmt.Errorf("error decoding Ollama pull progress: %w", err)
+			}
+			if message.Error != "" {
+				return PullProgress{}, errors.New(message.Error)
+			}
+			return PullProgress{Model: model, Status: message.Status, Completed: message.Completed, Total: message.Total}, nil
+		},
+		onProgress,
+	)
+}
+
+// pullModel posts the pull request and reports the progress sent as JSON lines
+func pullModel(ctx context.Context, url string, body map[string]any, parse func(line []byte) (PullProgress, error), onProgress func(progress PullProgress)) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(response.Body)
+		return &StatusError{
+			Method:     http.MethodPost,
+			URL:        url,
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Body:       strings.TrimSpace(string(message)),
+		}
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		progress, err := parse(line)
+		if err != nil {
+			return err
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+	return scanner.Err()
+}
+
+// modelCheck checks (and pulls) the model before the first completion,
+// until a check succeeds (a failed check is run again by the next completion)
+type modelCheck struct {
+	mutex      sync.Mutex
+	done       bool
+	pull       bool
+	onProgress func(progress PullProgress)
+}
+
+// WithModelCheck checks that the model is available on the provider before the first completion,
+// the first completion returns ErrModelNotFound if it is not
+func WithModelCheck() EngineOption {
+	return func(engine *Engine) {
+		engine.modelCheck = &modelCheck{}
+	}
+}
+
+// WithModelPull checks that the model is available on the provider before the first completion,
+// and pulls it if it is not, reporting the progress to onProgress (can be nil)
+func WithModelPull(onProgress func(progress PullProgress)) EngineOption {
+	return func(engine *Engine) {
+		engine.modelCheck = &modelCheck{pull: true, onProgress: onProgress}
+	}
+}
+
+// checkModel runs the model check of WithModelCheck or WithModelPull, until it succeeds
+func (e *Engine) checkModel() error {
+	if e.modelCheck == nil {
+		return nil
+	}
+	e.modelCheck.mutex.Lock()
+	defer e.modelCheck.mutex.Unlock()
+	if e.modelCheck.done {
+		return nil
+	}
+	if err := e.EnsureModel(e.modelCheck.pull, e.modelCheck.onProgress); err != nil {
+		return err
+	}
+	e.modelCheck.done = true
+	return nil
+}
+
+// EnsureModel checks that the model is available on the provider
+// (/models for Docker Model Runner, /api/tags for the native Ollama API).
+// If it is not and pull is true, it pulls the model when the provider is a ModelPuller
+// (Docker Model Runner, Ollama), else it returns ErrModelNotFound.
+// The requests are retried with the retry policy of the engine (without its timeouts for the pull)
+func (e *Engine) EnsureModel(pull bool, onProgress func(progress PullProgress)) error {
+	var models []string
+	err := e.withRetry(func(ctx context.Context) (bool, error) {
+		var err error
+		models, err = e.backend.listModels(ctx)
+		return true, err
+	})
+	if err != nil {
+		return fmt.Errorf("error listing the models: %w", err)
+	}
+	if hasModel(models, e.model) {
+		return nil
+	}
+	if !pull {
+		return fmt.Errorf("%w: %s", ErrModelNotFound, e.model)
+	}
+
+	puller, ok := e.provider.(ModelPuller)
+	if !ok {
+		return fmt.Errorf("%w: %s (the provider cannot pull models)", ErrModelNotFound, e.model)
+	}
+	// A pull takes longer than a completion
+	policy := e.retryPolicy
+	policy.RequestTimeout, policy.TotalTimeout = 0, 0
+	err = e.withRetryPolicy(policy, func(ctx context.Context) (bool, error) {
+		return true, puller.PullModel(ctx, e.model, onProgress)
+	})
+	if err != nil {
+		return fmt.Errorf("error pulling %s: %w", e.model, err)
+	}
+	return nil
+}
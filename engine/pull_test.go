@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// modelRunner is a fake Docker Model Runner: it lists its models, pulls them and answers the completions
+type modelRunner struct {
+	models []string
+	// failedPulls is the number of the next pulls which fail
+	failedPulls int
+	// lists, pulls and completions count the requests
+	lists, pulls, completions int
+}
+
+func (m *modelRunner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/engines/llama.cpp/v1/models":
+		m.lists++
+		writeModels(w, m.models...)
+	case "/models/create":
+		m.pulls++
+		var body struct {
+			From string `json:"from"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if m.failedPulls > 0 {
+			m.failedPulls--
+			fmt.Fprintln(w, `{"type":"error","message":"no space left on device"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"type":"progress","message":"Downloaded 50 MB","total":100,"pulled":50}`)
+		fmt.Fprintln(w, `{"type":"progress","message":"Downloaded 100 MB","total":100,"pulled":100}`)
+		fmt.Fprintln(w, `{"type":"success","message":"Model pulled successfully"}`)
+		m.models = append(m.models, body.From)
+	case "/engines/llama.cpp/v1/chat/completions":
+		m.completions++
+		writeCompletion(w, "tool_calls", "", toolCall("view_cart", `{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// runnerEngine returns an engine of the model m on the fake Docker Model Runner
+func runnerEngine(t *testing.T, runner *modelRunner, options ...EngineOption) *Engine {
+	t.Helper()
+	server := httptest.NewServer(runner)
+	t.Cleanup(server.Close)
+	provider := DockerModelRunnerEndpoint{OpenAICompatible(server.URL+"/engines/llama.cpp/v1/", "")}
+	options = append([]EngineOption{WithProvider(context.Background(), provider), WithModel("m"), WithRetryPolicy(testRetryPolicy())}, options...)
+	return NewEngine(options...)
+}
+
+func TestEnsureModel(t *testing.T) {
+	tests := []struct {
+		name   string
+		models []string
+		pull   bool
+		// progress is the reported progress, pulls the number of pull requests
+		progress []string
+		pulls    int
+		err      error
+	}{
+		{name: "model present", models: []string{"other", "m:latest"}, pull: true},
+		{name: "model missing", models: []string{"other"}, err: ErrModelNotFound},
+		{
+			name:     "model pulled",
+			models:   []string{"other"},
+			pull:     true,
+			progress: []string{"Downloaded 50 MB 50%", "Downloaded 100 MB 100%", "Model pulled successfully 0%"},
+			pulls:    1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := &modelRunner{models: test.models}
+			pullEngine := runnerEngine(t, runner)
+			progress := []string{}
+			err := pullEngine.EnsureModel(test.pull, func(p PullProgress) {
+				if p.Model != "m" {
+					t.Errorf("progress of the model %q", p.Model)
+				}
+				progress = append(progress, fmt.Sprintf("%s %.0f%%", p.Status, p.Percent()))
+			})
+			if (test.err == nil && err != nil) || !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if strings.Join(progress, ", ") != strings.Join(test.progress, ", ") || runner.pulls != test.pulls {
+				t.Errorf("progress %v, pulls %d, want %v, %d", progress, runner.pulls, test.progress, test.pulls)
+			}
+		})
+	}
+}
+
+func TestEnsureModelCannotPull(t *testing.T) {
+	// An OpenAI-compatible provider is not a ModelPuller
+	pullEngine := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		writeModels(w, "other")
+	})
+	if err := pullEngine.EnsureModel(true, nil); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("error %v, want %v", err, ErrModelNotFound)
+	}
+}
+
+func TestModelPull(t *testing.T) {
+	runner := &modelRunner{models: []string{"other"}, failedPulls: 1}
+	pullEngine := runnerEngine(t, runner, WithModelPull(nil))
+
+	// The failed pull fails the completion, without a completion request
+	if _, err := pullEngine.CompleteWithTools(nil); err == nil || !strings.Contains(err.Error(), "no space left on device") {
+		t.Fatalf("error %v, want the pull error", err)
+	}
+	if runner.pulls != 1 || runner.completions != 0 {
+		t.Errorf("pulls %d, completions %d, want 1, 0", runner.pulls, runner.completions)
+	}
+
+	// The failed check runs again with the next completion
+	if _, err := pullEngine.CompleteWithTools(nil); err != nil {
+		t.Fatal(err)
+	}
+	if runner.pulls != 2 || runner.completions != 1 || !slices.Contains(runner.models, "m") {
+		t.Errorf("pulls %d, completions %d, models %v, want 2, 1 and m", runner.pulls, runner.completions, runner.models)
+	}
+
+	// The model is checked once
+	lists := runner.lists
+	if _, err := pullEngine.CompleteWithTools(nil); err != nil {
+		t.Fatal(err)
+	}
+	if runner.lists != lists || runner.pulls != 2 || runner.completions != 2 {
+		t.Errorf("lists %d, pulls %d, completions %d, want %d, 2, 2", runner.lists, runner.pulls, runner.completions, lists)
+	}
+}
+
+func TestOllamaPullModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "qwen3" || !body.Stream {
+			t.Errorf("pull request %+v", body)
+		}
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling 6a0746a1ec1a","total":200,"completed":50}`)
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	}))
+	defer server.Close()
+
+	var progress []string
+	err := OllamaEndpoint{OpenAICompatible(server.URL+"/v1", "")}.PullModel(context.Background(), "qwen3", func(p PullProgress) {
+		progress = append(progress, fmt.Sprintf("%s %.0f%%", p.Status, p.Percent()))
+	})
+	if err == nil || err.Error() != "pull model manifest: file does not exist" {
+		t.Errorf("error %v, want the error of the pull", err)
+	}
+	if strings.Join(progress, ", ") != "pulling manifest 0%, pulling 6a0746a1ec1a 25%" {
+		t.Errorf("progress %v", progress)
+	}
+}
@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ErrUnknownTool is returned when the model calls a tool that is not registered
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Tool binds a tool definition to a Go handler
+type Tool struct {
+	Param openai.ChatCompletionToolParam
+	call  func(arguments string) (string, error)
+}
+
+// Name returns the name of the tool
+func (t Tool) Name() string {
+	return t.Param.Function.Name
+}
+
+// ToolOption changes the parameters of a tool created by NewTool
+type ToolOption func(tool *toolConfig)
+
+type toolConfig struct {
+	name       string
+	parameters openai.FunctionParameters
+	// aliases are the accepted aliases of the values of the properties, by property
+	aliases map[string]map[string]string
+}
+
+// property returns the schema of a property of the tool parameters, it panics if there is no such property
+func (c *toolConfig) property(name string) map[string]any {
+	properties, _ := c.parameters["properties"].(map[string]any)
+	property, ok := properties[name].(map[string]any)
+	if !ok {
+		panic(fmt.Sprintf("engine: no property %s in the parameters of tool %s", name, c.name))
+	}
+	return property
+}
+
+// WithEnum sets the allowed values of a string property of the tool parameters,
+// for the values known at runtime only (the enum tag is for the static ones)
+func WithEnum(property string, values ...string) ToolOption {
+	return func(tool *toolConfig) {
+		enum := make([]any, 0, len(values))
+		for _, value := range values {
+			enum = append(enum, value)
+		}
+		tool.property(property)["enum"] = enum
+	}
+}
+
+// WithAliases makes a tool accept aliases of the values of a string property, e.g. "tech" for "electronics":
+// before the validation, a value matching an alias (case-insensitive) is replaced by its value,
+// a value matching an enum value with another case is replaced by the enum value,
+// and an empty value is removed (it is a missing value)
+func WithAliases(property string, aliases map[string]string) ToolOption {
+	return func(tool *toolConfig) {
+		tool.property(property)
+		if tool.aliases == nil {
+			tool.aliases = map[string]map[string]string{}
+		}
+		if tool.aliases[property] == nil {
+			tool.aliases[property] = map[string]string{}
+		}
+		for alias, value := range aliases {
+			tool.aliases[property][strings.ToLower(alias)] = value
+		}
+	}
+}
+
+// NewTool creates a tool with a typed handler:
+// the parameters of the tool are generated from the Args struct (see ParametersOf)
+// then changed by the options (see WithEnum and WithAliases),
+// and the arguments of the tool calls are validated against this schema (see ValidateArguments)
+// then unmarshalled into it, after setting the fields to their default values
+func NewTool[Args any](name, description string, handler func(args Args) (string, error), options ...ToolOption) Tool {
+	config := toolConfig{name: name, parameters: ParametersOf[Args]()}
+	for _, option := range options {
+		option(&config)
+	}
+	parameters := config.parameters
+	schema, err := parseSchema(parameters)
+	if err != nil {
+		panic(fmt.Sprintf("engine: invalid parameters schema for tool %s: %v", name, err))
+	}
+	return Tool{
+		Param: openai.ChatCompletionToolParam{
+			Function: openai.FunctionDefinitionParam{
+				Name:        name,
+				Description: openai.String(description),
+				Parameters:  parameters,
+			},
+		},
+		call: func(arguments string) (string, error) {
+			arguments = schema.resolveAliases(arguments, config.aliases)
+			// Check the arguments against the schema before running the handler
+			arguments, err := schema.validateArguments(arguments)
+			if err != nil {
+				return "", fmt.Errorf("invalid %s arguments: %w", name, err)
+			}
+			var args Args
+			applyDefaults(reflect.ValueOf(&args))
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("error unmarshalling %s arguments: %w", name, err)
+			}
+			return handler(args)
+		},
+	}
+}
+
+// Registry dispatches the tool calls to the registered tools
+type Registry struct {
+	tools map[string]Tool
+	// names keeps the registration order for the tools catalog
+	names []string
+}
+
+// NewRegistry creates a registry with the given tools
+func NewRegistry(tools ...Tool) *Registry {
+	registry := &Registry{
+		tools: map[string]Tool{},
+	}
+	registry.Register(tools...)
+	return registry
+}
+
+// Register adds (or replaces) tools in the registry
+func (r *Registry) Register(tools ...Tool) {
+	for _, tool := range tools {
+		if _, exists := r.tools[tool.Name()]; !exists {
+			r.names = append(r.names, tool.Name())
+		}
+		r.tools[tool.Name()] = tool
+	}
+}
+
+// Tools returns the tools catalog to give to Engine.Tools
+func (r *Registry) Tools() []openai.ChatCompletionToolParam {
+	tools := make([]openai.ChatCompletionToolParam, 0, len(r.names))
+	for _, name := range r.names {
+		tools = append(tools, r.tools[name].Param)
+	}
+	return tools
+}
+
+// Execute parses the arguments of the tool call and runs the handler of the tool.
+// It can be used as the ToolExecutor of Engine.Run
+func (r *Registry) Execute(toolCall openai.ChatCompletionMessageToolCall) (string, error) {
+	tool, ok := r.tools[toolCall.Function.Name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, toolCall.Function.Name)
+	}
+	return tool.call(toolCall.Function.Arguments)
+}
+
+// ToolMessage executes the tool call and always returns a tool message answering it:
+// the content of the tool on success, or a structured error (see ToolErrorContent) on failure,
+// so the model can correct itself and the conversation has no unanswered tool call.
+// The error is also returned to be displayed or logged
+func (r *Registry) ToolMessage(toolCall openai.ChatCompletionMessageToolCall) (openai.ChatCompletionMessageParamUnion, error) {
+	content, err := r.Execute(toolCall)
+	if err != nil {
+		return openai.ToolMessage(ToolErrorContent(toolCall, err), toolCall.ID), err
+	}
+	return openai.ToolMessage(content, toolCall.ID), nil
+}
+
+// ToolError is the structured error sent back to the model when a tool call fails
+type ToolError struct {
+	Tool    string           `json:"tool"`
+	Message string           `json:"error"`
+	Details ValidationErrors `json:"details,omitempty"`
+}
+
+// ToolErrorContent returns the JSON content of the tool message for a failed tool call
+func ToolErrorContent(toolCall openai.ChatCompletionMessageToolCall, err error) string {
+	toolError := ToolError{
+		Tool:    toolCall.Function.Name,
+		Message: err.Error(),
+	}
+	var validationErrors ValidationErrors
+	if errors.As(err, &validationErrors) {
+		toolError.Details = validationErrors
+	}
+	content, marshalErr := json.Marshal(toolError)
+	if marshalErr != nil {
+		return fmt.Sprintf("Error: %s", err)
+	}
+	return string(content)
+}
+
+// resolveAliases replaces the aliases of the values of the properties of the arguments (see WithAliases).
+// The invalid JSON arguments are returned as is, for the validation to report them
+func (s *jsonSchema) resolveAliases(arguments string, aliases map[string]map[string]string) string {
+	if len(aliases) == 0 {
+		return arguments
+	}
+	decoder := json.NewDecoder(strings.NewReader(arguments))
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil || values == nil {
+		return arguments
+	}
+
+	changed := false
+	for property, propertyAliases := range aliases {
+		value, ok := values[property].(string)
+		if !ok {
+			continue
+		}
+		resolved, found := strings.TrimSpace(value), false
+		if resolved == "" {
+			delete(values, property)
+			changed = true
+			continue
+		}
+		if schema := s.Properties[property]; schema != nil {
+			for _, enumValue := range schema.Enum {
+				if text, ok := enumValue.(string); ok && strings.EqualFold(text, resolved) {
+					resolved, found = text, true
+					break
+				}
+			}
+		}
+		if alias, ok := propertyAliases[strings.ToLower(resolved)]; ok && !found {
+			resolved = alias
+		}
+		if resolved != value {
+			values[property] = resolved
+			changed = true
+		}
+	}
+	if !changed {
+		return arguments
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return arguments
+	}
+	return string(data)
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestNewToolAliases(t *testing.T) {
+	type searchArgs struct {
+		Query    string `json:"query,omitempty"`
+		Category string `json:"category,omitempty"`
+	}
+	search := NewTool("search_products", "Search for products",
+		func(args searchArgs) (string, error) {
+			return args.Category, nil
+		},
+		WithEnum("category", "books", "electronics", "electronics/phones"),
+		WithAliases("category", map[string]string{"Tech": "electronics", "book": "books", "phones": "electronics/phones"}),
+	)
+	registry := NewRegistry(search)
+
+	tests := []struct {
+		name      string
+		arguments string
+		// category is the category given to the handler, problems the "field problem" of the validation errors
+		category string
+		problems []string
+	}{
+		{name: "enum value", arguments: `{"category":"books"}`, category: "books"},
+		{name: "enum value with another case", arguments: `{"category":"Electronics"}`, category: "electronics"},
+		{name: "alias", arguments: `{"category":"tech"}`, category: "electronics"},
+		{name: "alias with another case", arguments: `{"category":"BOOK"}`, category: "books"},
+		{name: "alias with spaces", arguments: `{"category":" phones "}`, category: "electronics/phones"},
+		{name: "empty value", arguments: `{"query":"dune","category":""}`, category: ""},
+		{name: "missing value", arguments: `{"query":"dune"}`, category: ""},
+		{name: "not in the enum", arguments: `{"category":"garden"}`, problems: []string{"category enum"}},
+		{name: "not a string", arguments: `{"category":3}`, problems: []string{"category type"}},
+		{name: "invalid JSON", arguments: `{"category":`, problems: []string{" invalid_json"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, err := registry.Execute(openai.ChatCompletionMessageToolCall{
+				Function: openai.ChatCompletionMessageToolCallFunction{Name: "search_products", Arguments: test.arguments},
+			})
+			problems := []string{}
+			var validationErrors ValidationErrors
+			if errors.As(err, &validationErrors) {
+				for _, validationError := range validationErrors {
+					problems = append(problems, validationError.Field+" "+validationError.Problem)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(problems) != len(test.problems) {
+				t.Fatalf("problems %v, want %v", problems, test.problems)
+			}
+			for index := range problems {
+				if problems[index] != test.problems[index] {
+					t.Errorf("problems %v, want %v", problems, test.problems)
+				}
+			}
+			if err == nil && content != test.category {
+				t.Errorf("category %q, want %q", content, test.category)
+			}
+		})
+	}
+}
+
+func TestRegistryExecuteUnknownTool(t *testing.T) {
+	_, err := NewRegistry().Execute(openai.ChatCompletionMessageToolCall{
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: "checkout", Arguments: `{}`},
+	})
+	if !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("error %v, want %v", err, ErrUnknownTool)
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openai/openai-go"
+)
+
+var (
+	// ErrNoChoices is returned when the server returns a completion without choices
+	ErrNoChoices = errors.New("completion without choices")
+	// ErrTruncated is returned when the completion stopped on the max tokens (finish reason "length")
+	ErrTruncated = errors.New("completion truncated")
+	// ErrContentFiltered is returned when the completion was stopped by a content filter
+	ErrContentFiltered = errors.New("completion content filtered")
+)
+
+// CompletionResult is the result of a tool completion
+type CompletionResult struct {
+	// ToolCalls are all the tool calls of the first choice
+	ToolCalls []openai.ChatCompletionMessageToolCall
+	// Content is the text content, when the model answers in prose instead of calling tools
+	Content string
+	// FinishReason is the finish reason of the first choice ("stop", "tool_calls", "length", ...)
+	FinishReason string
+	// Usage is the token usage of the completion
+	Usage openai.CompletionUsage
+	// Message is the assistant message of the first choice
+	Message openai.ChatCompletionMessage
+	// Response is the raw response of the server
+	Response *openai.ChatCompletion
+}
+
+func newCompletionResult(completion *openai.ChatCompletion) (CompletionResult, error) {
+	result := CompletionResult{
+		Usage:    completion.Usage,
+		Response: completion,
+	}
+	if len(completion.Choices) == 0 {
+		return result, ErrNoChoices
+	}
+
+	choice := completion.Choices[0]
+	result.Message = choice.Message
+	result.ToolCalls = choice.Message.ToolCalls
+	result.Content = choice.Message.Content
+	result.FinishReason = choice.FinishReason
+
+	switch choice.FinishReason {
+	case "length":
+		return result, fmt.Errorf("%w: %d completion tokens", ErrTruncated, completion.Usage.CompletionTokens)
+	case "content_filter":
+		return result, ErrContentFiltered
+	}
+	return result, nil
+}
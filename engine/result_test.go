@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func TestNewCompletionResult(t *testing.T) {
+	choice := func(finishReason, content string, toolCalls ...openai.ChatCompletionMessageToolCall) []openai.ChatCompletionChoice {
+		return []openai.ChatCompletionChoice{{
+			FinishReason: finishReason,
+			Message:      openai.ChatCompletionMessage{Role: "assistant", Content: content, ToolCalls: toolCalls},
+		}}
+	}
+	tests := []struct {
+		name    string
+		choices []openai.ChatCompletionChoice
+		// toolCalls is the number of tool calls of the result
+		toolCalls int
+		content   string
+		err       error
+	}{
+		{name: "tool calls", choices: choice("tool_calls", "", toolCall("view_cart", `{}`), toolCall("checkout", `{}`)), toolCalls: 2},
+		{name: "stop", choices: choice("stop", "Hello"), content: "Hello"},
+		{name: "stop with tool calls", choices: choice("stop", "", toolCall("view_cart", `{}`)), toolCalls: 1},
+		{name: "length", choices: choice("length", "Hel", toolCall("view_cart", `{"a":`)), toolCalls: 1, content: "Hel", err: ErrTruncated},
+		{name: "content filter", choices: choice("content_filter", ""), err: ErrContentFiltered},
+		{name: "no choices", err: ErrNoChoices},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			completion := &openai.ChatCompletion{
+				Choices: test.choices,
+				Usage:   openai.CompletionUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			}
+			result, err := newCompletionResult(completion)
+			if (test.err == nil && err != nil) || !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			// The result is filled even with an error, e.g. to display the truncated content
+			if len(result.ToolCalls) != test.toolCalls || result.Content != test.content {
+				t.Errorf("tool calls %d, content %q, want %d, %q", len(result.ToolCalls), result.Content, test.toolCalls, test.content)
+			}
+			if len(test.choices) > 0 && result.FinishReason != test.choices[0].FinishReason {
+				t.Errorf("finish reason %q, want %q", result.FinishReason, test.choices[0].FinishReason)
+			}
+			if result.Usage.TotalTokens != 15 || result.Response != completion {
+				t.Errorf("usage %+v, response %p, want %p", result.Usage, result.Response, completion)
+			}
+		})
+	}
+}
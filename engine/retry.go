@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// RetryPolicy is the retry policy of the requests of the engine.
+// Local runners often return 503 while a model is still loading into memory
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request (1 means no retry)
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between two attempts
+	MaxBackoff time.Duration
+	// Multiplier is the growth factor of the delay after each retry
+	Multiplier float64
+	// Jitter is the random part of the delay, from 0 (none) to 1 (± the whole delay)
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes worth a retry
+	RetryableStatusCodes []int
+	// RequestTimeout is the timeout of each attempt (0 for none)
+	RequestTimeout time.Duration
+	// TotalTimeout is the timeout of all the attempts, delays included (0 for none)
+	TotalTimeout time.Duration
+	// OnRetry is called before each retry (can be nil), to log it
+	OnRetry func(retry Retry)
+}
+
+// Retry describes a retry, for the OnRetry hook
+type Retry struct {
+	// Attempt is the number of the failed attempt (starting at 1)
+	Attempt int
+	// Err is the error of the failed attempt
+	Err error
+	// Delay is the delay before the next attempt
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of the engine unless WithRetryPolicy is used:
+// 3 attempts with an exponential backoff from 500ms to 8s, on 408, 429, 500, 502, 503 and 504
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     8 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the completions (streaming or not)
+func WithRetryPolicy(policy RetryPolicy) EngineOption {
+	return func(engine *Engine) {
+		engine.retryPolicy = policy
+	}
+}
+
+// StatusError is the error of an HTTP request answered with an unexpected status code
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %q: %s %s", e.Method, e.URL, e.Status, e.Body))
+}
+
+// delay returns the delay after the given failed attempt, with the jitter
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(math.Max(p.Multiplier, 1), float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(math.Max(delay, 0))
+}
+
+// retryable tells if the error of an attempt is worth a retry
+func (p RetryPolicy) retryable(ctx context.Context, err error) bool {
+	// The caller cancelled the request (or the total timeout expired)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiError *openai.Error
+	if errors.As(err, &apiError) {
+		return slices.Contains(p.RetryableStatusCodes, apiError.StatusCode)
+	}
+	var statusError *StatusError
+	if errors.As(err, &statusError) {
+		return slices.Contains(p.RetryableStatusCodes, statusError.StatusCode)
+	}
+
+	// The timeout of the attempt expired
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// Transport errors (connection refused, reset, ...)
+	var netError net.Error
+	var opError *net.OpError
+	return errors.As(err, &netError) || errors.As(err, &opError)
+}
+
+// withRetry runs the request with the retry policy of the engine.
+// The request gets the context of each attempt, and tells with retryable if a failed attempt can be retried
+// (e.g. a stream cannot be retried once chunks were delivered)
+func (e *Engine) withRetry(request func(ctx context.Context) (retryable bool, err error)) error {
+	return e.withRetryPolicy(e.retryPolicy, request)
+}
+
+// withRetryPolicy runs the request with a retry policy (see withRetry)
+func (e *Engine) withRetryPolicy(policy RetryPolicy, request func(ctx context.Context) (retryable bool, err error)) error {
+	ctx := e.ctx
+	if policy.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.TotalTimeout)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.RequestTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.RequestTimeout)
+		}
+		retryable, err := request(attemptCtx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || !retryable || !policy.retryable(ctx, err) {
+			if attempt > 1 {
+				return fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+
+		delay := policy.delay(attempt)
+		if policy.OnRetry != nil {
+			policy.OnRetry(Retry{Attempt: attempt, Err: err, Delay: delay})
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("after %d attempts: %w (%w)", attempt, ctx.Err(), err)
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// testRetryPolicy is the default retry policy without delays
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = time.Millisecond
+	return policy
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, Multiplier: 2}
+	want := []time.Duration{100, 200, 400, 500, 500}
+	for i, delay := range want {
+		if got := policy.delay(i + 1); got != delay*time.Millisecond {
+			t.Errorf("delay after the attempt %d: %s, want %s", i+1, got, delay*time.Millisecond)
+		}
+	}
+
+	// A multiplier below 1 does not shrink the delay
+	policy.Multiplier = 0.5
+	if got := policy.delay(3); got != 100*time.Millisecond {
+		t.Errorf("delay with a multiplier of 0.5: %s, want 100ms", got)
+	}
+
+	// The jitter is ± Jitter of the delay
+	policy = RetryPolicy{InitialBackoff: time.Second, Multiplier: 2, Jitter: 0.2}
+	lowest, highest := time.Hour, time.Duration(0)
+	for range 1000 {
+		delay := policy.delay(2)
+		lowest, highest = min(lowest, delay), max(highest, delay)
+	}
+	if lowest < 1600*time.Millisecond || highest > 2400*time.Millisecond || highest-lowest < 400*time.Millisecond {
+		t.Errorf("delays with jitter from %s to %s, want from 1.6s to 2.4s", lowest, highest)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		retryable bool
+	}{
+		{name: "API 503", err: &openai.Error{StatusCode: http.StatusServiceUnavailable}, retryable: true},
+		{name: "API 429", err: &openai.Error{StatusCode: http.StatusTooManyRequests}, retryable: true},
+		{name: "API 400", err: &openai.Error{StatusCode: http.StatusBadRequest}},
+		{name: "API 404", err: &openai.Error{StatusCode: http.StatusNotFound}},
+		{name: "wrapped status 502", err: fmt.Errorf("error: %w", &StatusError{StatusCode: http.StatusBadGateway}), retryable: true},
+		{name: "status 408", err: &StatusError{StatusCode: http.StatusRequestTimeout}, retryable: true},
+		{name: "status 401", err: &StatusError{StatusCode: http.StatusUnauthorized}},
+		{name: "attempt timeout", err: context.DeadlineExceeded, retryable: true},
+		{name: "transport error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, retryable: true},
+		{name: "other error", err: errors.New("invalid JSON")},
+		{name: "cancelled", ctx: cancelled, err: &openai.Error{StatusCode: http.StatusServiceUnavailable}},
+	}
+	for _, test := range tests {
+		ctx := test.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if got := DefaultRetryPolicy().retryable(ctx, test.err); got != test.retryable {
+			t.Errorf("%s: retryable %v, want %v", test.name, got, test.retryable)
+		}
+	}
+}
+
+func TestWithRetryPolicyAttempts(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		// maxAttempts is the MaxAttempts of the policy, attempts the expected number of requests
+		maxAttempts int
+		attempts    int
+		err         string
+	}{
+		{name: "two 503 then 200", statuses: []int{503, 503, 200}, maxAttempts: 3, attempts: 3},
+		{name: "no retry left", statuses: []int{503, 503, 200}, maxAttempts: 2, attempts: 2, err: "after 2 attempts"},
+		{name: "not retryable", statuses: []int{400, 200}, maxAttempts: 3, attempts: 1, err: "400 Bad Request"},
+		{name: "no retry", statuses: []int{503, 200}, maxAttempts: 1, attempts: 1, err: "503 Service Unavailable"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			policy := testRetryPolicy()
+			policy.MaxAttempts = test.maxAttempts
+			var retries []Retry
+			policy.OnRetry = func(retry Retry) { retries = append(retries, retry) }
+			toolEngine := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[attempts]
+				attempts++
+				if status != http.StatusOK {
+					writeError(w, status, "model loading")
+					return
+				}
+				writeCompletion(w, "tool_calls", "", toolCall("view_cart", `{}`))
+			}, WithRetryPolicy(policy))
+
+			_, err := toolEngine.CompleteWithTools(nil)
+			if attempts != test.attempts || len(retries) != test.attempts-1 {
+				t.Errorf("%d attempts and %d retries, want %d attempts", attempts, len(retries), test.attempts)
+			}
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+// streamBackend is a backend whose streams fail with a 503, after delivering a chunk or not
+type streamBackend struct {
+	backend
+	chunks   int
+	attempts *int
+}
+
+func (b streamBackend) stream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(chunk openai.ChatCompletionChunk)) error {
+	*b.attempts++
+	for range b.chunks {
+		onChunk(openai.ChatCompletionChunk{})
+	}
+	return &StatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+}
+
+func TestStreamRetry(t *testing.T) {
+	for _, test := range []struct {
+		chunks   int
+		attempts int
+	}{
+		{chunks: 0, attempts: 3},
+		// A stream which delivered chunks cannot be replayed
+		{chunks: 1, attempts: 1},
+	} {
+		attempts := 0
+		streamEngine := NewEngine(WithModel("m"), WithRetryPolicy(testRetryPolicy()))
+		streamEngine.backend = streamBackend{chunks: test.chunks, attempts: &attempts}
+		if err := streamEngine.stream(openai.ChatCompletionNewParams{}, func(chunk openai.ChatCompletionChunk) {}); err == nil {
+			t.Errorf("%d chunks: no error", test.chunks)
+		}
+		if attempts != test.attempts {
+			t.Errorf("%d chunks: %d attempts, want %d", test.chunks, attempts, test.attempts)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package scenario
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/whales-collective/function-calling/engine"
+)
+
+// Benchmark is the statistics of several runs (trials) of a scenario with an engine
+type Benchmark struct {
+	Scenario string
+	Engine   string
+	// Trials is the number of runs, Errors the number of failed completions
+	Trials int
+	Errors int
+	// Passed is the number of runs with exactly the expected tool calls
+	Passed int
+	// ToolAccuracy is the mean ratio of the tool calls with the right tool
+	// (over the expected tool calls, or over the tool calls of the model when it made more)
+	ToolAccuracy float64
+	// ArgumentAccuracy is the mean ratio of the tool calls with the right tool and the right arguments
+	ArgumentAccuracy float64
+	// CallCountAccuracy is the ratio of the runs with the expected number of tool calls
+	CallCountAccuracy float64
+	// P50, P90 and P99 are the latency percentiles of the completions
+	P50, P90, P99 time.Duration
+	// EndToEndTokensPerSecond is the number of completion tokens per second of latency:
+	// the latency includes the prompt processing and the network, it is not the generation speed
+	EndToEndTokensPerSecond float64
+	// Results are the results of the runs
+	Results []Result
+	// Err is the error of the last failed completion
+	Err error
+}
+
+// RunBenchmark runs a scenario several times with an engine and computes the statistics of the runs;
+// the failed completions count as runs without any right tool call.
+// Each trial has its own seed (seed, seed+1, ...) on top of the options: use a temperature above 0
+// (e.g. engine.WithTemperature(0.7)), else the trials are the same deterministic request
+func RunBenchmark(toolEngine *engine.Engine, scenario Scenario, trials int, seed int64, options ...engine.CompletionOption) Benchmark {
+	results := make([]Result, 0, trials)
+	errors := 0
+	var lastErr error
+	for trial := range trials {
+		trialOptions := append(slices.Clone(options), engine.WithSeed(seed+int64(trial)))
+		result, err := Run(toolEngine, scenario, trialOptions...)
+		if err != nil {
+			errors++
+			lastErr = err
+			continue
+		}
+		results = append(results, result)
+	}
+	benchmark := NewBenchmark(scenario, toolEngine.String(), results, errors)
+	benchmark.Err = lastErr
+	return benchmark
+}
+
+// NewBenchmark computes the statistics of the results of a scenario with an engine
+// (errors is the number of runs without result)
+func NewBenchmark(scenario Scenario, engineName string, results []Result, errors int) Benchmark {
+	benchmark := Benchmark{
+		Scenario: scenario.Name,
+		Engine:   engineName,
+		Trials:   len(results) + errors,
+		Errors:   errors,
+		Results:  results,
+	}
+	if benchmark.Trials == 0 {
+		return benchmark
+	}
+
+	// The accuracies are the means of the accuracies of the runs (0 for the failed completions)
+	toolAccuracy, argumentAccuracy, rightCallCounts := 0.0, 0.0, 0
+	var durations []time.Duration
+	var duration time.Duration
+	completionTokens := int64(0)
+	for _, result := range results {
+		evaluation := result.Evaluation
+		calls := max(evaluation.Expected, evaluation.Actual)
+		if calls == 0 {
+			// No tool call expected, and none made
+			toolAccuracy++
+			argumentAccuracy++
+		} else {
+			toolAccuracy += float64(evaluation.ToolsMatched) / float64(calls)
+			argumentAccuracy += float64(evaluation.ArgumentsMatched) / float64(calls)
+		}
+		if evaluation.Actual == evaluation.Expected {
+			rightCallCounts++
+		}
+		if result.Passed() {
+			benchmark.Passed++
+		}
+		durations = append(durations, result.Duration)
+		duration += result.Duration
+		completionTokens += result.Usage.CompletionTokens
+	}
+	benchmark.ToolAccuracy = toolAccuracy / float64(benchmark.Trials)
+	benchmark.ArgumentAccuracy = argumentAccuracy / float64(benchmark.Trials)
+	benchmark.CallCountAccuracy = float64(rightCallCounts) / float64(benchmark.Trials)
+
+	slices.Sort(durations)
+	benchmark.P50 = percentile(durations, 50)
+	benchmark.P90 = percentile(durations, 90)
+	benchmark.P99 = percentile(durations, 99)
+	if duration > 0 {
+		benchmark.EndToEndTokensPerSecond = float64(completionTokens) / duration.Seconds()
+	}
+	return benchmark
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(durations))))
+	return durations[max(rank, 1)-1]
+}
+
+func (b Benchmark) String() string {
+	return fmt.Sprintf("📊 %s (%s): %d/%d passed, tools %.0f%%, arguments %.0f%%, call count %.0f%%, p50 %s, p90 %s, %.1f end-to-end tokens/s",
+		b.Scenario, b.Engine, b.Passed, b.Trials,
+		100*b.ToolAccuracy, 100*b.ArgumentAccuracy, 100*b.CallCountAccuracy,
+		b.P50.Round(time.Millisecond), b.P90.Round(time.Millisecond), b.EndToEndTokensPerSecond)
+}
+
+// MarkdownTable returns the statistics of the benchmarks as a Markdown table, to paste in the docs
+func MarkdownTable(benchmarks []Benchmark) string {
+	var table strings.Builder
+	table.WriteString("| Scenario | Engine | Trials | Passed | Tool accuracy | Argument accuracy | Call count accuracy | p50 | p90 | p99 | End-to-end tokens/s | Errors |\n")
+	table.WriteString("|----------|--------|-------:|-------:|--------------:|------------------:|--------------------:|----:|----:|----:|--------------------:|-------:|\n")
+	for _, b := range benchmarks {
+		fmt.Fprintf(&table, "| %s | %s | %d | %d | %.0f%% | %.0f%% | %.0f%% | %s | %s | %s | %.1f | %d |\n",
+			b.Scenario, markdownEscape(b.Engine), b.Trials, b.Passed,
+			100*b.ToolAccuracy, 100*b.ArgumentAccuracy, 100*b.CallCountAccuracy,
+			b.P50.Round(time.Millisecond), b.P90.Round(time.Millisecond), b.P99.Round(time.Millisecond),
+			b.EndToEndTokensPerSecond, b.Errors)
+	}
+	return table.String()
+}
+
+func markdownEscape(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
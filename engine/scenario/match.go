@@ -0,0 +1,336 @@
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// Evaluation is the evaluation of tool calls against the expected tool calls of a scenario
+type Evaluation struct {
+	// Expected is the number of expected tool calls
+	Expected int
+	// Actual is the number of tool calls of the model
+	Actual int
+	// ToolsMatched is the number of expected tool calls answered with the right tool
+	ToolsMatched int
+	// ArgumentsMatched is the number of expected tool calls answered with the right tool and the right arguments
+	ArgumentsMatched int
+	// Failures are the problems (missing, unexpected or wrong tool calls, wrong order)
+	Failures []string
+}
+
+// Passed is true when the tool calls are the expected ones
+func (e Evaluation) Passed() bool {
+	return len(e.Failures) == 0
+}
+
+// Evaluate checks the tool calls of the model against the expected tool calls
+func (s Scenario) Evaluate(toolCalls []openai.ChatCompletionMessageToolCall) Evaluation {
+	evaluation := Evaluation{Expected: len(s.Expected.ToolCalls), Actual: len(toolCalls)}
+	if s.Expected.Ordered {
+		evaluation.evaluateOrdered(s.Expected.ToolCalls, toolCalls)
+	} else {
+		evaluation.evaluateUnordered(s.Expected.ToolCalls, toolCalls)
+	}
+	return evaluation
+}
+
+// evaluateOrdered aligns the tool calls with the expected ones in order (a weighted longest common subsequence:
+// a tool call with the right arguments weighs more than a tool call of the right tool only),
+// so a missing or an unexpected tool call is reported once, without shifting the next ones
+func (e *Evaluation) evaluateOrdered(expected []ExpectedToolCall, toolCalls []openai.ChatCompletionMessageToolCall) {
+	// problems[i][j] are the problems of the arguments of toolCalls[j] for expected[i] (nil for another tool)
+	problems := make([][][]string, len(expected))
+	weight := func(i, j int) int {
+		switch {
+		case toolCalls[j].Function.Name != expected[i].Name:
+			return 0
+		case len(problems[i][j]) == 0:
+			return 2
+		}
+		return 1
+	}
+	for i := range expected {
+		problems[i] = make([][]string, len(toolCalls))
+		for j := range toolCalls {
+			if toolCalls[j].Function.Name == expected[i].Name {
+				problems[i][j] = expected[i].match(toolCalls[j])
+			}
+		}
+	}
+
+	// scores[i][j] is the best alignment score of expected[i:] and toolCalls[j:]
+	scores := make([][]int, len(expected)+1)
+	for i := range scores {
+		scores[i] = make([]int, len(toolCalls)+1)
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		for j := len(toolCalls) - 1; j >= 0; j-- {
+			scores[i][j] = max(scores[i+1][j], scores[i][j+1])
+			if w := weight(i, j); w > 0 {
+				scores[i][j] = max(scores[i][j], scores[i+1][j+1]+w)
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(expected) || j < len(toolCalls) {
+		switch {
+		case i < len(expected) && j < len(toolCalls) && weight(i, j) > 0 && scores[i][j] == scores[i+1][j+1]+weight(i, j):
+			e.ToolsMatched++
+			if len(problems[i][j]) > 0 {
+				e.Failures = append(e.Failures, fmt.Sprintf("#%d %s: %s", i+1, expected[i].Name, strings.Join(problems[i][j], "; ")))
+			} else {
+				e.ArgumentsMatched++
+			}
+			i, j = i+1, j+1
+		case i < len(expected) && (j == len(toolCalls) || scores[i][j] == scores[i+1][j]):
+			e.Failures = append(e.Failures, fmt.Sprintf("#%d %s: missing", i+1, expected[i]))
+			i++
+		default:
+			e.Failures = append(e.Failures, fmt.Sprintf("#%d unexpected %s %s", j+1, toolCalls[j].Function.Name, toolCalls[j].Function.Arguments))
+			j++
+		}
+	}
+}
+
+// evaluateUnordered matches the tool calls whatever their order:
+// first the tool calls with the right arguments, then the tool calls of the right tool
+func (e *Evaluation) evaluateUnordered(expected []ExpectedToolCall, toolCalls []openai.ChatCompletionMessageToolCall) {
+	remaining := slices.Clone(toolCalls)
+
+	var wrongArguments []ExpectedToolCall
+	for _, expectedToolCall := range expected {
+		index := slices.IndexFunc(remaining, func(toolCall openai.ChatCompletionMessageToolCall) bool {
+			return toolCall.Function.Name == expectedToolCall.Name && len(expectedToolCall.match(toolCall)) == 0
+		})
+		if index < 0 {
+			wrongArguments = append(wrongArguments, expectedToolCall)
+			continue
+		}
+		e.ToolsMatched++
+		e.ArgumentsMatched++
+		remaining = slices.Delete(remaining, index, index+1)
+	}
+
+	for _, expectedToolCall := range wrongArguments {
+		index := slices.IndexFunc(remaining, func(toolCall openai.ChatCompletionMessageToolCall) bool {
+			return toolCall.Function.Name == expectedToolCall.Name
+		})
+		if index < 0 {
+			e.Failures = append(e.Failures, fmt.Sprintf("%s: missing", expectedToolCall))
+			continue
+		}
+		e.ToolsMatched++
+		problems := expectedToolCall.match(remaining[index])
+		e.Failures = append(e.Failures, fmt.Sprintf("%s: %s", expectedToolCall.Name, strings.Join(problems, "; ")))
+		remaining = slices.Delete(remaining, index, index+1)
+	}
+
+	for _, toolCall := range remaining {
+		e.Failures = append(e.Failures, fmt.Sprintf("unexpected %s %s", toolCall.Function.Name, toolCall.Function.Arguments))
+	}
+}
+
+func (c ExpectedToolCall) String() string {
+	arguments, _ := json.Marshal(c.Arguments)
+	if c.Arguments == nil {
+		arguments = []byte("{}")
+	}
+	return c.Name + " " + string(arguments)
+}
+
+// match returns the problems of the arguments of a tool call (none when they match)
+func (c ExpectedToolCall) match(toolCall openai.ChatCompletionMessageToolCall) []string {
+	arguments := map[string]any{}
+	if strings.TrimSpace(toolCall.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &arguments); err != nil {
+			return []string{fmt.Sprintf("invalid JSON arguments %s", toolCall.Function.Arguments)}
+		}
+	}
+
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(c.Arguments)) {
+		value, present := arguments[name]
+		problems = append(problems, matchValue(name, c.Arguments[name], value, present)...)
+	}
+	if !c.Partial {
+		for _, name := range slices.Sorted(maps.Keys(arguments)) {
+			if _, expected := c.Arguments[name]; !expected {
+				problems = append(problems, fmt.Sprintf("%s: unexpected argument %s", name, jsonOf(arguments[name])))
+			}
+		}
+	}
+	return problems
+}
+
+// matchValue returns the problems of a value against an expected value or a matcher
+func matchValue(path string, expected, actual any, present bool) []string {
+	if matcher, ok := asMatcher(expected); ok {
+		return matchOperators(path, matcher, actual, present)
+	}
+	if !present {
+		return []string{fmt.Sprintf("%s: missing, expected %s", path, jsonOf(expected))}
+	}
+
+	switch expected := expected.(type) {
+	case map[string]any:
+		object, ok := actual.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, jsonOf(actual))}
+		}
+		var problems []string
+		for _, name := range slices.Sorted(maps.Keys(expected)) {
+			value, present := object[name]
+			problems = append(problems, matchValue(path+"."+name, expected[name], value, present)...)
+		}
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			if _, ok := expected[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: unexpected argument %s", path, name, jsonOf(object[name])))
+			}
+		}
+		return problems
+	case []any:
+		list, ok := actual.([]any)
+		if !ok || len(list) != len(expected) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, jsonOf(expected), jsonOf(actual))}
+		}
+		var problems []string
+		for index := range expected {
+			problems = append(problems, matchValue(fmt.Sprintf("%s[%d]", path, index), expected[index], list[index], true)...)
+		}
+		return problems
+	}
+
+	if !equalValues(expected, actual) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, jsonOf(expected), jsonOf(actual))}
+	}
+	return nil
+}
+
+// asMatcher returns the operators of a matcher (an object whose keys all start with $)
+func asMatcher(expected any) (map[string]any, bool) {
+	object, ok := expected.(map[string]any)
+	if !ok || len(object) == 0 {
+		return nil, false
+	}
+	for key := range object {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return object, true
+}
+
+// matchOperators returns the problems of a value against the operators of a matcher
+func matchOperators(path string, matcher map[string]any, actual any, present bool) []string {
+	if absent, _ := matcher["$absent"].(bool); absent {
+		if present {
+			return []string{fmt.Sprintf("%s: unexpected argument %s", path, jsonOf(actual))}
+		}
+		return nil
+	}
+	if !present {
+		return []string{fmt.Sprintf("%s: missing, expected %s", path, jsonOf(matcher))}
+	}
+
+	var problems []string
+	fail := func(expected string) {
+		problems = append(problems, fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonOf(actual)))
+	}
+	text, isText := actual.(string)
+	number, isNumber := toFloat(actual)
+
+	for _, operator := range slices.Sorted(maps.Keys(matcher)) {
+		operand := matcher[operator]
+		switch operator {
+		case "$any":
+		case "$ignore_case":
+			if !isText || !strings.EqualFold(text, fmt.Sprint(operand)) {
+				fail(fmt.Sprintf("%s (ignoring case)", jsonOf(operand)))
+			}
+		case "$contains":
+			if !isText || !strings.Contains(strings.ToLower(text), strings.ToLower(fmt.Sprint(operand))) {
+				fail(fmt.Sprintf("a string containing %s", jsonOf(operand)))
+			}
+		case "$regex":
+			pattern, err := regexp.Compile(fmt.Sprint(operand))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid $regex %s: %v", path, jsonOf(operand), err))
+			} else if !isText || !pattern.MatchString(text) {
+				fail(fmt.Sprintf("a string matching %s", jsonOf(operand)))
+			}
+		case "$one_of":
+			values, _ := operand.([]any)
+			if !slices.ContainsFunc(values, func(value any) bool { return equalValues(value, actual) }) {
+				fail(fmt.Sprintf("one of %s", jsonOf(operand)))
+			}
+		case "$min":
+			bound, _ := toFloat(operand)
+			if !isNumber || number < bound {
+				fail(fmt.Sprintf("a number >= %s", jsonOf(operand)))
+			}
+		case "$max":
+			bound, _ := toFloat(operand)
+			if !isNumber || number > bound {
+				fail(fmt.Sprintf("a number <= %s", jsonOf(operand)))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown matcher %s", path, operator))
+		}
+	}
+	return problems
+}
+
+// equalValues compares an expected value (decoded from YAML) and an argument (decoded from JSON):
+// the numbers are compared as numbers, so 10 and 10.0 are equal
+func equalValues(expected, actual any) bool {
+	expectedNumber, expectedIsNumber := toFloat(expected)
+	actualNumber, actualIsNumber := toFloat(actual)
+	if expectedIsNumber || actualIsNumber {
+		return expectedIsNumber && actualIsNumber && expectedNumber == actualNumber
+	}
+	return reflect.DeepEqual(normalize(expected), normalize(actual))
+}
+
+// normalize returns the JSON form of a value decoded from YAML (e.g. the ints become float64)
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func toFloat(value any) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+func jsonOf(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
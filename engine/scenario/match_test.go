@@ -0,0 +1,213 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"gopkg.in/yaml.v3"
+)
+
+func toolCall(name, arguments string) openai.ChatCompletionMessageToolCall {
+	return openai.ChatCompletionMessageToolCall{
+		Function: openai.ChatCompletionMessageToolCallFunction{Name: name, Arguments: arguments},
+	}
+}
+
+func orderedScenario() Scenario {
+	return Scenario{Expected: Expected{Ordered: true, ToolCalls: []ExpectedToolCall{
+		{Name: "search_products", Arguments: map[string]any{"query": "Dune"}},
+		{Name: "add_to_cart", Arguments: map[string]any{"product_name": "iPad Pro 12.9", "quantity": 3}},
+		{Name: "add_to_cart", Arguments: map[string]any{"product_name": "Sapiens", "quantity": 5}},
+		{Name: "view_cart"},
+		{Name: "checkout"},
+	}}}
+}
+
+func TestEvaluateOrdered(t *testing.T) {
+	search := toolCall("search_products", `{"query":"Dune"}`)
+	addIPad := toolCall("add_to_cart", `{"product_name":"iPad Pro 12.9","quantity":3}`)
+	addSapiens := toolCall("add_to_cart", `{"product_name":"Sapiens","quantity":5}`)
+	viewCart := toolCall("view_cart", `{}`)
+	checkout := toolCall("checkout", `{}`)
+
+	tests := []struct {
+		name             string
+		toolCalls        []openai.ChatCompletionMessageToolCall
+		toolsMatched     int
+		argumentsMatched int
+		failures         []string
+	}{
+		{
+			name:             "all the calls",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{search, addIPad, addSapiens, viewCart, checkout},
+			toolsMatched:     5,
+			argumentsMatched: 5,
+		},
+		{
+			name:             "first call missing",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{addIPad, addSapiens, viewCart, checkout},
+			toolsMatched:     4,
+			argumentsMatched: 4,
+			failures:         []string{`#1 search_products {"query":"Dune"}: missing`},
+		},
+		{
+			name:             "extra call in the middle",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{search, addIPad, viewCart, addSapiens, viewCart, checkout},
+			toolsMatched:     5,
+			argumentsMatched: 5,
+			failures:         []string{"#3 unexpected view_cart {}"},
+		},
+		{
+			name: "wrong arguments",
+			toolCalls: []openai.ChatCompletionMessageToolCall{
+				search, addIPad, toolCall("add_to_cart", `{"product_name":"Sapiens","quantity":2}`), viewCart, checkout,
+			},
+			toolsMatched:     5,
+			argumentsMatched: 4,
+			failures:         []string{"#3 add_to_cart: quantity: expected 5, got 2"},
+		},
+		{
+			name:             "swapped calls",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{search, addIPad, addSapiens, checkout, viewCart},
+			toolsMatched:     4,
+			argumentsMatched: 4,
+			failures:         []string{"#4 view_cart {}: missing", "#5 unexpected view_cart {}"},
+		},
+		{
+			name:      "no calls",
+			toolCalls: nil,
+			failures: []string{
+				`#1 search_products {"query":"Dune"}: missing`,
+				`#2 add_to_cart {"product_name":"iPad Pro 12.9","quantity":3}: missing`,
+				`#3 add_to_cart {"product_name":"Sapiens","quantity":5}: missing`,
+				"#4 view_cart {}: missing",
+				"#5 checkout {}: missing",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluation := orderedScenario().Evaluate(test.toolCalls)
+			if evaluation.ToolsMatched != test.toolsMatched || evaluation.ArgumentsMatched != test.argumentsMatched {
+				t.Errorf("tools matched %d, arguments matched %d, want %d and %d",
+					evaluation.ToolsMatched, evaluation.ArgumentsMatched, test.toolsMatched, test.argumentsMatched)
+			}
+			if strings.Join(evaluation.Failures, "\n") != strings.Join(test.failures, "\n") {
+				t.Errorf("failures:\n%s\nwant:\n%s", strings.Join(evaluation.Failures, "\n"), strings.Join(test.failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		// expected are the expected arguments in YAML, as in a scenario file
+		expected  string
+		partial   bool
+		arguments string
+		problems  []string
+	}{
+		{name: "equal", expected: "{name: Dune, quantity: 2}", arguments: `{"quantity":2,"name":"Dune"}`},
+		{name: "number as float", expected: "{quantity: 10}", arguments: `{"quantity":10.0}`},
+		{name: "no arguments", expected: "{}", arguments: ``},
+		{name: "different value", expected: "{name: Dune}", arguments: `{"name":"dune"}`, problems: []string{`name: expected "Dune", got "dune"`}},
+		{name: "missing", expected: "{name: Dune, quantity: 2}", arguments: `{"name":"Dune"}`, problems: []string{"quantity: missing, expected 2"}},
+		{name: "unexpected", expected: "{name: Dune}", arguments: `{"name":"Dune","limit":5}`, problems: []string{"limit: unexpected argument 5"}},
+		{name: "partial", expected: "{name: Dune}", partial: true, arguments: `{"name":"Dune","limit":5}`},
+		{name: "invalid JSON", expected: "{name: Dune}", arguments: `{"name":`, problems: []string{`invalid JSON arguments {"name":`}},
+		{name: "nested object", expected: "{filter: {category: books, max: 20}}", arguments: `{"filter":{"category":"books","max":25,"sort":"name"}}`,
+			problems: []string{"filter.max: expected 20, got 25", `filter.sort: unexpected argument "name"`}},
+		{name: "list", expected: "{ids: [1, 2]}", arguments: `{"ids":[1,2]}`},
+		{name: "list of another size", expected: "{ids: [1, 2]}", arguments: `{"ids":[1]}`, problems: []string{"ids: expected [1,2], got [1]"}},
+		{name: "$any", expected: "{name: {$any: true}}", arguments: `{"name":"anything"}`},
+		{name: "$any missing", expected: "{name: {$any: true}}", arguments: `{}`, problems: []string{`name: missing, expected {"$any":true}`}},
+		{name: "$absent", expected: "{limit: {$absent: true}}", partial: true, arguments: `{"name":"Dune"}`},
+		{name: "$absent present", expected: "{limit: {$absent: true}}", arguments: `{"limit":5}`, problems: []string{"limit: unexpected argument 5"}},
+		{name: "$ignore_case", expected: "{name: {$ignore_case: Dune}}", arguments: `{"name":"DUNE"}`},
+		{name: "$contains", expected: "{name: {$contains: ipad}}", arguments: `{"name":"iPad Pro 12.9"}`},
+		{name: "$contains not a string", expected: "{name: {$contains: ipad}}", arguments: `{"name":12}`, problems: []string{`name: expected a string containing "ipad", got 12`}},
+		{name: "$regex", expected: `{name: {$regex: "^iPad Pro"}}`, arguments: `{"name":"iPad Pro 12.9"}`},
+		{name: "$regex no match", expected: `{name: {$regex: "^iPad Pro$"}}`, arguments: `{"name":"iPad Pro 12.9"}`, problems: []string{`name: expected a string matching "^iPad Pro$", got "iPad Pro 12.9"`}},
+		{name: "invalid $regex", expected: `{name: {$regex: "("}}`, arguments: `{"name":"Dune"}`, problems: []string{"name: invalid $regex \"(\": error parsing regexp: missing closing ): `(`"}},
+		{name: "$one_of", expected: "{size: {$one_of: [S, M]}}", arguments: `{"size":"M"}`},
+		{name: "$one_of numbers", expected: "{quantity: {$one_of: [1, 2]}}", arguments: `{"quantity":2.0}`},
+		{name: "$one_of other", expected: "{size: {$one_of: [S, M]}}", arguments: `{"size":"L"}`, problems: []string{`size: expected one of ["S","M"], got "L"`}},
+		{name: "$min and $max", expected: "{quantity: {$min: 1, $max: 5}}", arguments: `{"quantity":5}`},
+		{name: "out of bounds", expected: "{quantity: {$min: 1, $max: 5}, limit: {$min: 1}}", arguments: `{"quantity":6,"limit":0}`,
+			problems: []string{"limit: expected a number >= 1, got 0", "quantity: expected a number <= 5, got 6"}},
+		{name: "bound not a number", expected: "{quantity: {$min: 1}}", arguments: `{"quantity":"3"}`, problems: []string{`quantity: expected a number >= 1, got "3"`}},
+		{name: "unknown matcher", expected: "{name: {$like: Dune}}", arguments: `{"name":"Dune"}`, problems: []string{"name: unknown matcher $like"}},
+		{name: "object with a $ key", expected: "{filter: {$min: 1, max: 2}}", arguments: `{"filter":{"$min":1,"max":2}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := ExpectedToolCall{Name: "tool", Partial: test.partial}
+			if err := yaml.Unmarshal([]byte(test.expected), &expected.Arguments); err != nil {
+				t.Fatal(err)
+			}
+			problems := expected.match(toolCall("tool", test.arguments))
+			if strings.Join(problems, "\n") != strings.Join(test.problems, "\n") {
+				t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(problems, "\n"), strings.Join(test.problems, "\n"))
+			}
+		})
+	}
+}
+
+func TestEvaluateUnordered(t *testing.T) {
+	scenario := orderedScenario()
+	scenario.Expected.Ordered = false
+	search := toolCall("search_products", `{"query":"Dune"}`)
+	addIPad := toolCall("add_to_cart", `{"product_name":"iPad Pro 12.9","quantity":3}`)
+	addSapiens := toolCall("add_to_cart", `{"product_name":"Sapiens","quantity":5}`)
+	viewCart := toolCall("view_cart", `{}`)
+	checkout := toolCall("checkout", `{}`)
+
+	tests := []struct {
+		name             string
+		toolCalls        []openai.ChatCompletionMessageToolCall
+		toolsMatched     int
+		argumentsMatched int
+		failures         []string
+	}{
+		{
+			name:             "any order",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{checkout, addSapiens, viewCart, search, addIPad},
+			toolsMatched:     5,
+			argumentsMatched: 5,
+		},
+		{
+			// The add_to_cart of the iPad matches its expected call, whatever the order
+			name: "wrong arguments",
+			toolCalls: []openai.ChatCompletionMessageToolCall{
+				toolCall("add_to_cart", `{"product_name":"Sapiens","quantity":2}`), addIPad, search, viewCart, checkout,
+			},
+			toolsMatched:     5,
+			argumentsMatched: 4,
+			failures:         []string{"add_to_cart: quantity: expected 5, got 2"},
+		},
+		{
+			name:             "missing and unexpected",
+			toolCalls:        []openai.ChatCompletionMessageToolCall{search, addIPad, addSapiens, viewCart, viewCart},
+			toolsMatched:     4,
+			argumentsMatched: 4,
+			failures:         []string{"checkout {}: missing", "unexpected view_cart {}"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evaluation := scenario.Evaluate(test.toolCalls)
+			if evaluation.ToolsMatched != test.toolsMatched || evaluation.ArgumentsMatched != test.argumentsMatched {
+				t.Errorf("tools matched %d, arguments matched %d, want %d and %d",
+					evaluation.ToolsMatched, evaluation.ArgumentsMatched, test.toolsMatched, test.argumentsMatched)
+			}
+			if strings.Join(evaluation.Failures, "\n") != strings.Join(test.failures, "\n") {
+				t.Errorf("failures:\n%s\nwant:\n%s", strings.Join(evaluation.Failures, "\n"), strings.Join(test.failures, "\n"))
+			}
+			if evaluation.Passed() != (len(test.failures) == 0) {
+				t.Errorf("passed %v with the failures %v", evaluation.Passed(), evaluation.Failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package scenario
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Ranking is the statistics of an engine over all the scenarios of a sweep
+type Ranking struct {
+	// Rank starts at 1 (the best engine)
+	Rank   int
+	Engine string
+	// Scenarios is the number of scenarios
+	Scenarios int
+	// Trials, Passed and Errors are the sums of the runs of all the scenarios
+	Trials int
+	Passed int
+	Errors int
+	// ToolAccuracy, ArgumentAccuracy and CallCountAccuracy are the means over the scenarios
+	ToolAccuracy      float64
+	ArgumentAccuracy  float64
+	CallCountAccuracy float64
+	// P50 is the median latency of all the completions
+	P50 time.Duration
+	// EndToEndTokensPerSecond is the number of completion tokens per second of latency of all the completions
+	// (prompt processing and network included)
+	EndToEndTokensPerSecond float64
+}
+
+// Rank ranks the engines of the benchmarks over all their scenarios:
+// by argument accuracy, then tool accuracy, then call count accuracy, then median latency
+func Rank(benchmarks []Benchmark) []Ranking {
+	var engines []string
+	byEngine := map[string][]Benchmark{}
+	for _, benchmark := range benchmarks {
+		if _, ok := byEngine[benchmark.Engine]; !ok {
+			engines = append(engines, benchmark.Engine)
+		}
+		byEngine[benchmark.Engine] = append(byEngine[benchmark.Engine], benchmark)
+	}
+
+	rankings := make([]Ranking, 0, len(engines))
+	for _, engineName := range engines {
+		ranking := Ranking{Engine: engineName}
+		var durations []time.Duration
+		var duration time.Duration
+		completionTokens := int64(0)
+		for _, benchmark := range byEngine[engineName] {
+			ranking.Scenarios++
+			ranking.Trials += benchmark.Trials
+			ranking.Passed += benchmark.Passed
+			ranking.Errors += benchmark.Errors
+			ranking.ToolAccuracy += benchmark.ToolAccuracy
+			ranking.ArgumentAccuracy += benchmark.ArgumentAccuracy
+			ranking.CallCountAccuracy += benchmark.CallCountAccuracy
+			for _, result := range benchmark.Results {
+				durations = append(durations, result.Duration)
+				duration += result.Duration
+				completionTokens += result.Usage.CompletionTokens
+			}
+		}
+		ranking.ToolAccuracy /= float64(ranking.Scenarios)
+		ranking.ArgumentAccuracy /= float64(ranking.Scenarios)
+		ranking.CallCountAccuracy /= float64(ranking.Scenarios)
+		slices.Sort(durations)
+		ranking.P50 = percentile(durations, 50)
+		if duration > 0 {
+			ranking.EndToEndTokensPerSecond = float64(completionTokens) / duration.Seconds()
+		}
+		rankings = append(rankings, ranking)
+	}
+
+	// The engines without completions are the last ones
+	withoutCompletions := func(ranking Ranking) int {
+		if ranking.Errors == ranking.Trials {
+			return 1
+		}
+		return 0
+	}
+	slices.SortStableFunc(rankings, func(a, b Ranking) int {
+		return cmp.Or(
+			cmp.Compare(b.ArgumentAccuracy, a.ArgumentAccuracy),
+			cmp.Compare(b.ToolAccuracy, a.ToolAccuracy),
+			cmp.Compare(b.CallCountAccuracy, a.CallCountAccuracy),
+			cmp.Compare(withoutCompletions(a), withoutCompletions(b)),
+			cmp.Compare(a.P50, b.P50),
+		)
+	})
+	for index := range rankings {
+		rankings[index].Rank = index + 1
+	}
+	return rankings
+}
+
+func (r Ranking) String() string {
+	return fmt.Sprintf("🏆 #%d %s: %d/%d passed, tools %.0f%%, arguments %.0f%%, call count %.0f%%, p50 %s, %.1f end-to-end tokens/s",
+		r.Rank, r.Engine, r.Passed, r.Trials,
+		100*r.ToolAccuracy, 100*r.ArgumentAccuracy, 100*r.CallCountAccuracy,
+		r.P50.Round(time.Millisecond), r.EndToEndTokensPerSecond)
+}
+
+// RankingTable returns the ranking of the engines as a Markdown table
+func RankingTable(rankings []Ranking) string {
+	var table strings.Builder
+	table.WriteString("| Rank | Engine | Scenarios | Trials | Passed | Tool accuracy | Argument accuracy | Call count accuracy | p50 | End-to-end tokens/s | Errors |\n")
+	table.WriteString("|-----:|--------|----------:|-------:|-------:|--------------:|------------------:|--------------------:|----:|--------------------:|-------:|\n")
+	for _, r := range rankings {
+		fmt.Fprintf(&table, "| %d | %s | %d | %d | %d | %.0f%% | %.0f%% | %.0f%% | %s | %.1f | %d |\n",
+			r.Rank, markdownEscape(r.Engine), r.Scenarios, r.Trials, r.Passed,
+			100*r.ToolAccuracy, 100*r.ArgumentAccuracy, 100*r.CallCountAccuracy,
+			r.P50.Round(time.Millisecond), r.EndToEndTokensPerSecond, r.Errors)
+	}
+	return table.String()
+}
@@ -0,0 +1,70 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/whales-collective/function-calling/engine"
+)
+
+// Result is the result of a scenario run with an engine
+type Result struct {
+	// Scenario is the name of the scenario
+	Scenario string
+	// Engine is the provider and the model of the engine
+	Engine string
+	// ToolCalls are the tool calls of the model
+	ToolCalls []openai.ChatCompletionMessageToolCall
+	// Content is the text content, when the model answers in prose instead of calling tools
+	Content string
+	// Usage is the token usage of the completion
+	Usage openai.CompletionUsage
+	// Duration is the duration of the completion
+	Duration time.Duration
+	// Evaluation is the evaluation of the tool calls against the expected ones
+	Evaluation Evaluation
+}
+
+// Run sends the tools and the prompts of the scenario to the engine (a single tool completion)
+// and evaluates the tool calls against the expected ones.
+// The tools catalog of the engine is replaced by the tools of the scenario
+func Run(toolEngine *engine.Engine, scenario Scenario, options ...engine.CompletionOption) (Result, error) {
+	result := Result{Scenario: scenario.Name, Engine: toolEngine.String()}
+
+	toolEngine.Tools(scenario.ToolParams())
+	start := time.Now()
+	completion, err := toolEngine.CompleteWithTools(scenario.Messages(), options...)
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("error running scenario %s with %s: %w", scenario.Name, result.Engine, err)
+	}
+
+	result.ToolCalls = completion.ToolCalls
+	result.Content = completion.Content
+	result.Usage = completion.Usage
+	result.Evaluation = scenario.Evaluate(completion.ToolCalls)
+	return result, nil
+}
+
+// Passed is true when the tool calls are the expected ones
+func (r Result) Passed() bool {
+	return r.Evaluation.Passed()
+}
+
+// String returns the text report of the run, with a line per failure
+func (r Result) String() string {
+	var report strings.Builder
+	status := "✅"
+	if !r.Passed() {
+		status = "❌"
+	}
+	fmt.Fprintf(&report, "%s %s (%s): %d/%d tool calls with the right arguments, %d tool calls, %s",
+		status, r.Scenario, r.Engine,
+		r.Evaluation.ArgumentsMatched, r.Evaluation.Expected, r.Evaluation.Actual, r.Duration.Round(time.Millisecond))
+	for _, failure := range r.Evaluation.Failures {
+		fmt.Fprintf(&report, "\n   😠 %s", failure)
+	}
+	return report.String()
+}
@@ -0,0 +1,176 @@
+// Package scenario runs declarative function-calling test cases:
+// a YAML (or JSON) file describes the tools, the prompts and the expected tool calls,
+// and Run checks the tool calls of any engine against them.
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidScenario is returned when a scenario file is incomplete or inconsistent
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// Scenario is a function-calling test case
+type Scenario struct {
+	// Name is the name of the scenario (the file name without extension when empty)
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Tools is the tools catalog sent to the model
+	Tools []Tool `yaml:"tools"`
+	// System is the system prompt (optional)
+	System string `yaml:"system"`
+	// User is the user prompt
+	User string `yaml:"user"`
+	// Expected describes the expected tool calls
+	Expected Expected `yaml:"expected"`
+	// Path is the file of the scenario
+	Path string `yaml:"-"`
+}
+
+// Tool is a tool definition of a scenario, its parameters are a JSON schema
+type Tool struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Parameters  map[string]any `yaml:"parameters"`
+}
+
+// Expected describes the expected tool calls of a scenario
+type Expected struct {
+	// Ordered is true when the tool calls must come in this order
+	Ordered bool `yaml:"ordered"`
+	// ToolCalls are the expected tool calls (none: the model must not call tools)
+	ToolCalls []ExpectedToolCall `yaml:"tool_calls"`
+}
+
+// ExpectedToolCall is an expected tool call.
+// An argument is either a value (compared with the normalized JSON value of the argument)
+// or a matcher, an object of $ operators:
+//
+//	$any: true               any value, the argument must be present
+//	$absent: true            the argument must not be present
+//	$ignore_case: "Dune"     the same string whatever the case
+//	$contains: "dune"        a string containing this one (whatever the case)
+//	$regex: "^[Dd]une$"      a string matching the regular expression
+//	$one_of: [1, "one"]      one of the values
+//	$min: 1, $max: 5         a number between the bounds
+type ExpectedToolCall struct {
+	Name      string         `yaml:"name"`
+	Arguments map[string]any `yaml:"arguments"`
+	// Partial accepts arguments which are not in Arguments (e.g. optional arguments)
+	Partial bool `yaml:"partial"`
+}
+
+// Load reads a scenario file (.yaml, .yml or .json: YAML is a superset of JSON)
+func Load(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("error reading scenario %s: %w", path, err)
+	}
+	scenario.Path = path
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := scenario.validate(); err != nil {
+		return Scenario{}, fmt.Errorf("%w %s: %v", ErrInvalidScenario, path, err)
+	}
+	return scenario, nil
+}
+
+// LoadAll reads the scenario files, and the scenario files of the directories, sorted by name
+func LoadAll(paths ...string) ([]Scenario, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					files = append(files, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+	slices.Sort(files)
+
+	scenarios := make([]Scenario, 0, len(files))
+	for _, file := range files {
+		scenario, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// validate checks that the prompt and the tools are defined,
+// and that the expected tool calls use the tools of the scenario
+func (s Scenario) validate() error {
+	if strings.TrimSpace(s.User) == "" {
+		return errors.New("the user prompt is empty")
+	}
+	names := make([]string, 0, len(s.Tools))
+	for _, tool := range s.Tools {
+		if tool.Name == "" {
+			return errors.New("a tool has no name")
+		}
+		names = append(names, tool.Name)
+	}
+	for _, toolCall := range s.Expected.ToolCalls {
+		if !slices.Contains(names, toolCall.Name) {
+			return fmt.Errorf("the expected tool call %q is not a tool of the scenario", toolCall.Name)
+		}
+	}
+	return nil
+}
+
+// ToolParams returns the tools catalog of the scenario
+func (s Scenario) ToolParams() []openai.ChatCompletionToolParam {
+	tools := make([]openai.ChatCompletionToolParam, 0, len(s.Tools))
+	for _, tool := range s.Tools {
+		parameters := openai.FunctionParameters(tool.Parameters)
+		if parameters == nil {
+			parameters = openai.FunctionParameters{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, openai.ChatCompletionToolParam{
+			Function: openai.FunctionDefinitionParam{
+				Name:        tool.Name,
+				Description: openai.String(tool.Description),
+				Parameters:  parameters,
+			},
+		})
+	}
+	return tools
+}
+
+// Messages returns the messages of the scenario: the system prompt (if any) and the user prompt
+func (s Scenario) Messages() []openai.ChatCompletionMessageParamUnion {
+	var messages []openai.ChatCompletionMessageParamUnion
+	if s.System != "" {
+		messages = append(messages, openai.SystemMessage(s.System))
+	}
+	return append(messages, openai.UserMessage(s.User))
+}
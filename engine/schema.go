@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ParametersOf generates the JSON Schema of the tool parameters from the Args struct.
+// The schema is built from the struct fields and tags:
+//   - json: the name of the property; the fields with omitempty are optional, the others are required
+//   - description: the description of the property
+//   - enum: the comma-separated list of the allowed values
+//   - minimum, maximum: the bounds of a numeric property
+//   - default: the default value of the property (a field with a default value is optional)
+//
+// The fields of an embedded struct without a JSON name are properties of the struct, as with encoding/json.
+// ParametersOf panics if a tag value does not match the type of its field.
+func ParametersOf[Args any]() openai.FunctionParameters {
+	return openai.FunctionParameters(schemaOf(reflect.TypeFor[Args]()))
+}
+
+func schemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.Struct:
+		return objectSchemaOf(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func objectSchemaOf(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	var embedded []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isEmbeddedStruct(field) {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		property := schemaOf(field.Type)
+		if description, ok := field.Tag.Lookup("description"); ok {
+			property["description"] = description
+		}
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			values := []any{}
+			for _, value := range strings.Split(enum, ",") {
+				values = append(values, mustTagValue(field, "enum", strings.TrimSpace(value)))
+			}
+			property["enum"] = values
+		}
+		for _, bound := range []string{"minimum", "maximum"} {
+			if value, ok := field.Tag.Lookup(bound); ok {
+				number, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					panic(fmt.Sprintf("engine: invalid %s tag %q on field %s: %v", bound, value, field.Name, err))
+				}
+				property[bound] = number
+			}
+		}
+		_, hasDefault := field.Tag.Lookup("default")
+		if hasDefault {
+			property["default"] = mustTagValue(field, "default", field.Tag.Get("default"))
+		}
+		properties[name] = property
+
+		if !omitempty && !hasDefault {
+			required = append(required, name)
+		}
+	}
+
+	// The fields of the embedded structs are promoted as with encoding/json,
+	// the fields of the struct win over them (they are optional through a pointer)
+	for _, field := range embedded {
+		fieldType := field.Type
+		optional := fieldType.Kind() == reflect.Pointer
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		schema := objectSchemaOf(fieldType)
+		promoted := map[string]bool{}
+		for name, property := range schema["properties"].(map[string]any) {
+			if _, exists := properties[name]; !exists {
+				properties[name] = property
+				promoted[name] = true
+			}
+		}
+		embeddedRequired, _ := schema["required"].([]string)
+		for _, name := range embeddedRequired {
+			if promoted[name] && !optional {
+				required = append(required, name)
+			}
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// isEmbeddedStruct tells if a struct field is an embedded struct (or pointer to struct) without a JSON name,
+// whose fields are promoted to the fields of the struct by encoding/json
+func isEmbeddedStruct(field reflect.StructField) bool {
+	if !field.Anonymous || field.Tag.Get("json") == "-" {
+		return false
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return false
+	}
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Pointer {
+		// encoding/json cannot set a pointer to an unexported struct
+		if !field.IsExported() {
+			return false
+		}
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct
+}
+
+// jsonName returns the JSON name of a struct field and whether it is optional or skipped
+func jsonName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" || option == "omitzero" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// tagValue converts the raw value of a tag to the type of the field:
+// strings are used as is, the other types are decoded as JSON
+func tagValue(t reflect.Type, raw string) (reflect.Value, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	value := reflect.New(t).Elem()
+	if t.Kind() == reflect.String {
+		value.SetString(raw)
+		return value, nil
+	}
+	if err := json.Unmarshal([]byte(raw), value.Addr().Interface()); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+func mustTagValue(field reflect.StructField, tag, raw string) any {
+	value, err := tagValue(field.Type, raw)
+	if err != nil {
+		panic(fmt.Sprintf("engine: invalid %s tag %q on field %s: %v", tag, raw, field.Name, err))
+	}
+	return value.Interface()
+}
+
+// applyDefaults sets the fields of a struct to the values of their default tags
+func applyDefaults(value reflect.Value) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		// The exported fields of an embedded unexported struct can be set
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if raw, ok := field.Tag.Lookup("default"); ok && field.IsExported() {
+			defaultValue, err := tagValue(field.Type, raw)
+			if err != nil {
+				continue
+			}
+			if field.Type.Kind() == reflect.Pointer {
+				pointer := reflect.New(field.Type.Elem())
+				pointer.Elem().Set(defaultValue)
+				value.Field(i).Set(pointer)
+			} else {
+				value.Field(i).Set(defaultValue)
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			applyDefaults(value.Field(i))
+		}
+	}
+}
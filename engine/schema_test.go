@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+type Pagination struct {
+	Offset int `json:"offset,omitempty" minimum:"0"`
+	Limit  int `json:"limit,omitempty" default:"10"`
+}
+
+type ProductRef struct {
+	ProductName string `json:"product_name" description:"The name of the product"`
+}
+
+type pricing struct {
+	MaxPrice float64 `json:"max_price"`
+}
+
+func TestParametersOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters openai.FunctionParameters
+		want       string
+	}{
+		{
+			name: "fields and tags",
+			parameters: ParametersOf[struct {
+				Query   string   `json:"query" description:"Search query"`
+				SortBy  string   `json:"sort_by,omitempty" enum:"price,name"`
+				Tags    []string `json:"tags,omitempty"`
+				Ignored string   `json:"-"`
+				hidden  string
+			}](),
+			want: `{"properties":{"query":{"description":"Search query","type":"string"},"sort_by":{"enum":["price","name"],"type":"string"},"tags":{"items":{"type":"string"},"type":"array"}},"required":["query"],"type":"object"}`,
+		},
+		{
+			name: "embedded structs",
+			parameters: ParametersOf[struct {
+				ProductRef
+				*Pagination
+				pricing
+				Quantity int `json:"quantity"`
+			}](),
+			want: `{"properties":{"limit":{"default":10,"type":"integer"},"max_price":{"type":"number"},"offset":{"minimum":0,"type":"integer"},"product_name":{"description":"The name of the product","type":"string"},"quantity":{"type":"integer"}},"required":["quantity","product_name","max_price"],"type":"object"}`,
+		},
+		{
+			name: "shadowed embedded field",
+			parameters: ParametersOf[struct {
+				ProductRef
+				ProductName string `json:"product_name,omitempty" description:"The name or the ID of the product"`
+			}](),
+			want: `{"properties":{"product_name":{"description":"The name or the ID of the product","type":"string"}},"type":"object"}`,
+		},
+		{
+			name: "named embedded struct",
+			parameters: ParametersOf[struct {
+				Pagination `json:"page"`
+			}](),
+			want: `{"properties":{"page":{"properties":{"limit":{"default":10,"type":"integer"},"offset":{"minimum":0,"type":"integer"}},"type":"object"}},"required":["page"],"type":"object"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := json.Marshal(test.parameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(schema) != test.want {
+				t.Errorf("schema:\n%s\nwant:\n%s", schema, test.want)
+			}
+		})
+	}
+}
+
+func TestNewToolEmbeddedArgs(t *testing.T) {
+	type updateArgs struct {
+		ProductRef
+		pricing
+		Pagination
+	}
+	var got updateArgs
+	tool := NewTool("update", "Update a product", func(args updateArgs) (string, error) {
+		got = args
+		return "updated", nil
+	})
+	if _, err := tool.call(`{"product_name":"Dune","max_price":20}`); err != nil {
+		t.Fatal(err)
+	}
+	if got.ProductName != "Dune" || got.MaxPrice != 20 || got.Limit != 10 {
+		t.Errorf("arguments %+v, want Dune, 20 and the default limit", got)
+	}
+	if _, err := tool.call(`{"max_price":20}`); err == nil {
+		t.Errorf("no error without the required product_name of the embedded struct")
+	}
+}
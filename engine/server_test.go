@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// testEngine returns an engine of an OpenAI-compatible test server answering with handler
+func testEngine(t *testing.T, handler http.HandlerFunc, options ...EngineOption) *Engine {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	options = append([]EngineOption{WithProvider(context.Background(), OpenAICompatible(server.URL+"/v1", "")), WithModel("m")}, options...)
+	return NewEngine(options...)
+}
+
+// writeCompletion writes a chat completion with a single choice
+func writeCompletion(w http.ResponseWriter, finishReason, content string, toolCalls ...openai.ChatCompletionMessageToolCall) {
+	message := map[string]any{"role": "assistant", "content": content}
+	if len(toolCalls) > 0 {
+		calls := []any{}
+		for i, toolCall := range toolCalls {
+			calls = append(calls, map[string]any{
+				"id":       fmt.Sprintf("call_%d", i),
+				"type":     "function",
+				"function": map[string]any{"name": toolCall.Function.Name, "arguments": toolCall.Function.Arguments},
+			})
+		}
+		message["tool_calls"] = calls
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id": "x", "object": "chat.completion", "created": 1, "model": "m",
+		"choices": []any{map[string]any{"index": 0, "message": message, "finish_reason": finishReason}},
+		"usage":   map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
+
+// writeError writes an API error with a status code
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"message": message, "type": "invalid_request_error"}})
+}
@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openai/openai-go"
+)
+
+// toolCallsAssembler collects the streamed fragments of the tool calls (by index)
+// into complete tool calls
+type toolCallsAssembler struct {
+	// calls are the tool calls in the order of their first fragment
+	calls []*streamedToolCall
+	// current is the tool call receiving the fragments of each index
+	current    map[int64]*streamedToolCall
+	onToolCall func(toolCall openai.ChatCompletionMessageToolCall)
+}
+
+// streamedToolCall is a tool call being assembled
+type streamedToolCall struct {
+	index int64
+	call  openai.ChatCompletionMessageToolCall
+	// done is true when the tool call was sent to the callback
+	done bool
+}
+
+func newToolCallsAssembler(onToolCall func(toolCall openai.ChatCompletionMessageToolCall)) *toolCallsAssembler {
+	return &toolCallsAssembler{
+		current:    map[int64]*streamedToolCall{},
+		onToolCall: onToolCall,
+	}
+}
+
+// add appends a fragment to its tool call.
+// A fragment of a new tool call (a new index, or a new id on an index already seen)
+// means that the previous ones are complete
+func (a *toolCallsAssembler) add(fragment openai.ChatCompletionChunkChoiceDeltaToolCall) {
+	call, exists := a.current[fragment.Index]
+	if !exists || (fragment.ID != "" && call.call.ID != "" && fragment.ID != call.call.ID) {
+		a.flush(fragment.Index)
+		if exists {
+			a.send(call)
+		}
+		call = &streamedToolCall{index: fragment.Index, call: openai.ChatCompletionMessageToolCall{Type: "function"}}
+		a.current[fragment.Index] = call
+		a.calls = append(a.calls, call)
+	}
+	if fragment.ID != "" {
+		call.call.ID = fragment.ID
+	}
+	call.call.Function.Name += fragment.Function.Name
+	call.call.Function.Arguments += fragment.Function.Arguments
+}
+
+// flush sends the complete tool calls with an index lower than before to the callback
+func (a *toolCallsAssembler) flush(before int64) {
+	for _, call := range a.sorted() {
+		if call.index < before {
+			a.send(call)
+		}
+	}
+}
+
+// send sends a tool call to the callback, once
+func (a *toolCallsAssembler) send(call *streamedToolCall) {
+	if call.done {
+		return
+	}
+	call.done = true
+	if a.onToolCall != nil {
+		a.onToolCall(call.call)
+	}
+}
+
+// sorted returns the tool calls by index (then in the order of their first fragment)
+func (a *toolCallsAssembler) sorted() []*streamedToolCall {
+	calls := append([]*streamedToolCall{}, a.calls...)
+	sort.SliceStable(calls, func(i, j int) bool { return calls[i].index < calls[j].index })
+	return calls
+}
+
+// toolCalls sends the remaining tool calls to the callback and returns all the tool calls by index
+func (a *toolCallsAssembler) toolCalls() []openai.ChatCompletionMessageToolCall {
+	for _, call := range a.sorted() {
+		a.send(call)
+	}
+	return a.sent()
+}
+
+// sent returns the tool calls already sent to the callback, by index
+func (a *toolCallsAssembler) sent() []openai.ChatCompletionMessageToolCall {
+	toolCalls := []openai.ChatCompletionMessageToolCall{}
+	for _, call := range a.sorted() {
+		if call.done {
+			toolCalls = append(toolCalls, call.call)
+		}
+	}
+	return toolCalls
+}
+
+// ToolStreamCompletion runs a streaming completion with the tools catalog.
+// The fragments of the tool calls (index, id, name and argument chunks) are assembled
+// into complete tool calls, and onToolCall is called as soon as each tool call is complete,
+// so the tools can run before the whole response arrives.
+// It returns all the tool calls, in the order of their index.
+// When the stream fails, or stops on the max tokens (ErrTruncated) or on a content filter (ErrContentFiltered),
+// the last tool calls (maybe incomplete) are not sent to onToolCall,
+// and it returns the tool calls already sent to onToolCall with the error
+func (e *Engine) ToolStreamCompletion(messages []openai.ChatCompletionMessageParamUnion, onToolCall func(toolCall openai.ChatCompletionMessageToolCall), options ...CompletionOption) ([]openai.ChatCompletionMessageToolCall, error) {
+	if err := e.checkModel(); err != nil {
+		return nil, err
+	}
+	params := e.toolParams(messages, options)
+
+	assembler := newToolCallsAssembler(onToolCall)
+	finishReason := ""
+	err := e.stream(params, func(chunk openai.ChatCompletionChunk) {
+		if len(chunk.Choices) == 0 {
+			return
+		}
+		for _, fragment := range chunk.Choices[0].Delta.ToolCalls {
+			assembler.add(fragment)
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+	})
+	if err != nil {
+		return assembler.sent(), fmt.Errorf("error streaming tool completion: %w", err)
+	}
+
+	switch finishReason {
+	case "length":
+		return assembler.sent(), fmt.Errorf("error streaming tool completion: %w", ErrTruncated)
+	case "content_filter":
+		return assembler.sent(), fmt.Errorf("error streaming tool completion: %w", ErrContentFiltered)
+	}
+	return assembler.toolCalls(), nil
+}
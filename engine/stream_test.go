@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+// streamServer serves a streaming completion with the given deltas (the last one with the finish reason)
+func streamServer(t *testing.T, finishReason string, deltas ...string) *Engine {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, delta := range deltas {
+			finish := "null"
+			if i == len(deltas)-1 && finishReason != "" {
+				finish = `"` + finishReason + `"`
+			}
+			fmt.Fprintf(w, `data: {"id":"x","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":%s,"finish_reason":%s}]}`+"\n\n", delta, finish)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return NewEngine(WithProvider(context.Background(), OpenAICompatible(server.URL+"/v1", "")), WithModel("m"))
+}
+
+func TestToolStreamCompletion(t *testing.T) {
+	tests := []struct {
+		name         string
+		finishReason string
+		deltas       []string
+		// want are the tool calls sent to the callback (id name arguments), and returned
+		want []string
+		err  error
+	}{
+		{
+			name:         "fragments by index",
+			finishReason: "tool_calls",
+			deltas: []string{
+				`{"tool_calls":[{"index":0,"id":"a","type":"function","function":{"name":"hello","arguments":""}}]}`,
+				`{"tool_calls":[{"index":0,"function":{"arguments":"{\"name\":"}}]}`,
+				`{"tool_calls":[{"index":0,"function":{"arguments":"\"Bob\"}"}}]}`,
+				`{"tool_calls":[{"index":1,"id":"b","type":"function","function":{"name":"bye","arguments":"{}"}}]}`,
+				`{}`,
+			},
+			want: []string{`a hello {"name":"Bob"}`, "b bye {}"},
+		},
+		{
+			name:         "new id on the same index",
+			finishReason: "tool_calls",
+			deltas: []string{
+				`{"tool_calls":[{"index":0,"id":"a","type":"function","function":{"name":"hello","arguments":"{\"name\":\"Bob\"}"}}]}`,
+				`{"tool_calls":[{"index":0,"id":"b","type":"function","function":{"name":"hello","arguments":"{\"name\":\"Alice\"}"}}]}`,
+				`{}`,
+			},
+			want: []string{`a hello {"name":"Bob"}`, `b hello {"name":"Alice"}`},
+		},
+		{
+			name:         "truncated",
+			finishReason: "length",
+			deltas: []string{
+				`{"tool_calls":[{"index":0,"id":"a","type":"function","function":{"name":"hello","arguments":"{\"name\":\"Bob\"}"}}]}`,
+				`{"tool_calls":[{"index":1,"id":"b","type":"function","function":{"name":"hello","arguments":"{\"na"}}]}`,
+			},
+			want: []string{`a hello {"name":"Bob"}`},
+			err:  ErrTruncated,
+		},
+		{
+			name:         "content filtered",
+			finishReason: "content_filter",
+			deltas: []string{
+				`{"tool_calls":[{"index":0,"id":"a","type":"function","function":{"name":"hello","arguments":"{}"}}]}`,
+			},
+			want: []string{},
+			err:  ErrContentFiltered,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			toolEngine := streamServer(t, test.finishReason, test.deltas...)
+			format := func(toolCall openai.ChatCompletionMessageToolCall) string {
+				return toolCall.ID + " " + toolCall.Function.Name + " " + toolCall.Function.Arguments
+			}
+			dispatched := []string{}
+			toolCalls, err := toolEngine.ToolStreamCompletion(nil, func(toolCall openai.ChatCompletionMessageToolCall) {
+				dispatched = append(dispatched, format(toolCall))
+			})
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			returned := []string{}
+			for _, toolCall := range toolCalls {
+				returned = append(returned, format(toolCall))
+			}
+			if strings.Join(dispatched, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("dispatched:\n%s\nwant:\n%s", strings.Join(dispatched, "\n"), strings.Join(test.want, "\n"))
+			}
+			if strings.Join(returned, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("returned:\n%s\nwant:\n%s", strings.Join(returned, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
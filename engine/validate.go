@@ -0,0 +1,292 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// ValidationError describes a problem of a tool call argument
+type ValidationError struct {
+	// Field is the path of the argument, e.g. "quantity" or "items[0].name"
+	// (empty for the whole arguments object)
+	Field string `json:"field"`
+	// Problem is one of: invalid_json, missing, unexpected, type, enum, minimum, maximum
+	Problem string `json:"problem"`
+	// Expected is what the schema expects (a type, the allowed values, a bound)
+	Expected string `json:"expected,omitempty"`
+	// Got is what the model sent
+	Got string `json:"got,omitempty"`
+}
+
+func (e ValidationError) Error() string {
+	field := e.Field
+	if field == "" {
+		field = "arguments"
+	}
+	switch e.Problem {
+	case "missing":
+		return fmt.Sprintf("%s: required field is missing", field)
+	case "unexpected":
+		return fmt.Sprintf("%s: unexpected field", field)
+	case "enum":
+		return fmt.Sprintf("%s: expected one of %s, got %s", field, e.Expected, e.Got)
+	case "invalid_json":
+		return fmt.Sprintf("%s: invalid JSON: %s", field, e.Got)
+	default:
+		return fmt.Sprintf("%s: expected %s %s, got %s", field, e.Problem, e.Expected, e.Got)
+	}
+}
+
+// ValidationErrors are all the problems of the arguments of a tool call
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, validationError := range e {
+		messages = append(messages, validationError.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// jsonSchema is the subset of JSON Schema used to describe the tool parameters
+type jsonSchema struct {
+	Type                 any                    `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []any                  `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	AdditionalProperties any                    `json:"additionalProperties"`
+}
+
+// parseSchema normalizes the tool parameters (nested maps of any kind) into a jsonSchema
+func parseSchema(parameters openai.FunctionParameters) (*jsonSchema, error) {
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, err
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// ValidateArguments checks the JSON arguments of a tool call against the tool parameters schema.
+// It returns ValidationErrors listing every missing field, unexpected field,
+// wrong type, value out of the enum and value out of bounds.
+// An optional argument set to null is considered as missing.
+func ValidateArguments(parameters openai.FunctionParameters, arguments string) error {
+	schema, err := parseSchema(parameters)
+	if err != nil {
+		return fmt.Errorf("invalid tool parameters schema: %w", err)
+	}
+	_, err = schema.validateArguments(arguments)
+	return err
+}
+
+// validateArguments validates the arguments and returns them normalized:
+// the integral numbers are rewritten without decimals ("10.0" becomes "10")
+// so they can be unmarshalled into Go integers
+func (s *jsonSchema) validateArguments(arguments string) (string, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(arguments)))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return arguments, ValidationErrors{{Problem: "invalid_json", Got: err.Error()}}
+	}
+	// The arguments are a single JSON value, e.g. not two concatenated objects
+	if _, err := decoder.Token(); err != io.EOF {
+		return arguments, ValidationErrors{{Problem: "invalid_json", Got: fmt.Sprintf("unexpected data after the JSON value at offset %d", decoder.InputOffset())}}
+	}
+
+	var errs ValidationErrors
+	s.validate("", value, &errs)
+	if len(errs) > 0 {
+		return arguments, errs
+	}
+
+	normalized, err := json.Marshal(normalizeNumbers(value))
+	if err != nil {
+		return arguments, err
+	}
+	return string(normalized), nil
+}
+
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Float64(); err == nil && n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return json.Number(strconv.FormatInt(int64(n), 10))
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (s *jsonSchema) validate(path string, value any, errs *ValidationErrors) {
+	if expected := s.types(); len(expected) > 0 && !slices.ContainsFunc(expected, func(t string) bool { return matchesType(t, value) }) {
+		*errs = append(*errs, ValidationError{Field: path, Problem: "type", Expected: strings.Join(expected, " or "), Got: jsonType(value)})
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return sameValue(allowed, value) }) {
+		allowed := make([]string, 0, len(s.Enum))
+		for _, item := range s.Enum {
+			allowed = append(allowed, fmt.Sprint(item))
+		}
+		*errs = append(*errs, ValidationError{Field: path, Problem: "enum", Expected: strings.Join(allowed, ", "), Got: fmt.Sprint(value)})
+	}
+
+	if number, ok := value.(json.Number); ok {
+		n, _ := number.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			*errs = append(*errs, ValidationError{Field: path, Problem: "minimum", Expected: fmt.Sprint(*s.Minimum), Got: number.String()})
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			*errs = append(*errs, ValidationError{Field: path, Problem: "maximum", Expected: fmt.Sprint(*s.Maximum), Got: number.String()})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		s.validateObject(path, v, errs)
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	}
+}
+
+func (s *jsonSchema) validateObject(path string, object map[string]any, errs *ValidationErrors) {
+	for _, name := range s.Required {
+		if v, ok := object[name]; !ok || v == nil {
+			*errs = append(*errs, ValidationError{Field: joinPath(path, name), Problem: "missing"})
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := object[name]
+		property, known := s.Properties[name]
+		if !known {
+			if s.Properties != nil && s.AdditionalProperties != true {
+				*errs = append(*errs, ValidationError{Field: joinPath(path, name), Problem: "unexpected"})
+			}
+			continue
+		}
+		// An optional argument set to null is considered as missing
+		if value == nil {
+			continue
+		}
+		property.validate(joinPath(path, name), value, errs)
+	}
+}
+
+// types returns the allowed types of the schema ("type" is a string or a list of strings)
+func (s *jsonSchema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := []string{}
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func matchesType(expected string, value any) bool {
+	switch expected {
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		n, err := number.Float64()
+		return err == nil && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonType(value) == expected
+	}
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+// sameValue compares an enum value of the schema with a decoded argument
+func sameValue(allowed, value any) bool {
+	if number, ok := value.(json.Number); ok {
+		n, err := number.Float64()
+		if err != nil {
+			return false
+		}
+		a, ok := allowed.(float64)
+		return ok && a == n
+	}
+	return reflect.DeepEqual(allowed, value)
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func cartParameters() openai.FunctionParameters {
+	return openai.FunctionParameters{
+		"type": "object",
+		"properties": map[string]any{
+			"product_name": map[string]any{"type": "string"},
+			"quantity":     map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+			"size":         map[string]any{"type": "string", "enum": []string{"S", "M", "L"}},
+			"tags":         map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"note":         map[string]any{"type": []string{"string", "null"}},
+		},
+		"required": []string{"product_name", "quantity"},
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		// problems are the "field problem" of the validation errors
+		problems []string
+	}{
+		{name: "valid", arguments: `{"product_name":"iPad","quantity":3}`},
+		{name: "integral float", arguments: `{"product_name":"iPad","quantity":3.0}`},
+		{name: "optional null", arguments: `{"product_name":"iPad","quantity":3,"size":null,"note":null}`},
+		{name: "all the fields", arguments: `{"product_name":"iPad","quantity":3,"size":"M","tags":["a","b"],"note":"gift"}`},
+		{name: "empty", arguments: ``, problems: []string{"product_name missing", "quantity missing"}},
+		{name: "invalid JSON", arguments: `{"product_name":`, problems: []string{" invalid_json"}},
+		{name: "trailing object", arguments: `{"product_name":"iPad","quantity":3}{"quantity":4}`, problems: []string{" invalid_json"}},
+		{name: "trailing data", arguments: `{"product_name":"iPad","quantity":3} }`, problems: []string{" invalid_json"}},
+		{name: "trailing spaces", arguments: "{\"product_name\":\"iPad\",\"quantity\":3}\n "},
+		{name: "required null", arguments: `{"product_name":null,"quantity":3}`, problems: []string{"product_name missing"}},
+		{name: "unexpected", arguments: `{"product_name":"iPad","quantity":3,"color":"red"}`, problems: []string{"color unexpected"}},
+		{name: "wrong type", arguments: `{"product_name":"iPad","quantity":"3"}`, problems: []string{"quantity type"}},
+		{name: "not an integer", arguments: `{"product_name":"iPad","quantity":2.5}`, problems: []string{"quantity type"}},
+		{name: "bounds", arguments: `{"product_name":"iPad","quantity":0}`, problems: []string{"quantity minimum"}},
+		{name: "enum", arguments: `{"product_name":"iPad","quantity":11,"size":"XL"}`, problems: []string{"quantity maximum", "size enum"}},
+		{name: "items", arguments: `{"product_name":"iPad","quantity":1,"tags":["a",2]}`, problems: []string{"tags[1] type"}},
+		{name: "not an object", arguments: `[1]`, problems: []string{" type"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateArguments(cartParameters(), test.arguments)
+			problems := []string{}
+			var validationErrors ValidationErrors
+			if errors.As(err, &validationErrors) {
+				for _, validationError := range validationErrors {
+					problems = append(problems, validationError.Field+" "+validationError.Problem)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if strings.Join(problems, "\n") != strings.Join(test.problems, "\n") {
+				t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(problems, "\n"), strings.Join(test.problems, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateArgumentsNormalized(t *testing.T) {
+	schema, err := parseSchema(cartParameters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := schema.validateArguments(`{"product_name":"iPad","quantity":3.0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized != `{"product_name":"iPad","quantity":3}` {
+		t.Errorf("normalized %s", normalized)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/whales-collective/function-calling/engine"
+)
+
+// providers are the names of the providers of the -engine flag
+var providers = map[string]func(ctx context.Context) engine.EngineOption{
+	"dmr":    engine.WithDockerModelRunner,
+	"ollama": engine.WithOllama,
+	"ollama-native": func(ctx context.Context) engine.EngineOption {
+		return engine.WithOllamaNative(ctx, engine.OllamaNativeOptions{})
+	},
+	"llamacpp": func(ctx context.Context) engine.EngineOption {
+		return engine.WithProvider(ctx, engine.LlamaCpp())
+	},
+	"vllm": func(ctx context.Context) engine.EngineOption {
+		return engine.WithProvider(ctx, engine.VLLM())
+	},
+	"lmstudio": func(ctx context.Context) engine.EngineOption {
+		return engine.WithProvider(ctx, engine.LMStudio())
+	},
+}
+
+// engineFlags are the engines to run, as provider=model (e.g. dmr=ai/qwen2.5:0.5B-F16)
+type engineFlags []string
+
+func (f *engineFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *engineFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// newEngine creates the engine of a provider=model specification
+func newEngine(ctx context.Context, spec string) (*engine.Engine, error) {
+	provider, model, ok := strings.Cut(spec, "=")
+	if !ok || model == "" {
+		return nil, fmt.Errorf("invalid engine %q: expected provider=model", spec)
+	}
+	withProvider, ok := providers[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (dmr, ollama, ollama-native, llamacpp, vllm, lmstudio)", provider)
+	}
+	return engine.NewEngine(withProvider(ctx), engine.WithModel(model)), nil
+}
+
+// readEngines reads the engines of a sweep file: one provider=model per line,
+// the empty lines and the lines starting with # are ignored
+func readEngines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var engines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		engines = append(engines, line)
+	}
+	return engines, scanner.Err()
+}
@@ -0,0 +1,19 @@
+module scenarios
+
+go 1.24.0
+
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/whales-collective/function-calling/engine v0.0.0
+)
+
+require (
+	github.com/openai/openai-go v1.2.0 // indirect
+	github.com/tidwall/gjson v1.14.4 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.1 // indirect
+	github.com/tidwall/sjson v1.2.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/whales-collective/function-calling/engine => ../engine
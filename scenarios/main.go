@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/whales-collective/function-calling/engine"
+	"github.com/whales-collective/function-calling/engine/scenario"
+)
+
+func main() {
+	ctx := context.Background()
+	err := godotenv.Load()
+	if err != nil {
+		// use the env variables if there is no .env file
+	}
+
+	var engines engineFlags
+	flag.Var(&engines, "engine", "engine to run, as provider=model (repeatable), e.g. dmr=ai/qwen2.5:0.5B-F16 or ollama=qwen2.5:0.5b")
+	trials := flag.Int("trials", 1, "number of runs of each scenario with each engine (benchmark when > 1)")
+	markdown := flag.String("markdown", "", "file of the Markdown table of the benchmark (default: printed)")
+	sweep := flag.String("sweep", "", "file of the engines to sweep and rank, one provider=model per line (e.g. models.txt)")
+	temperature := flag.Float64("temperature", -1, fmt.Sprintf("sampling temperature (default: 0 for a run, %g for a benchmark)", benchmarkTemperature))
+	seed := flag.Int64("seed", 0, "seed of the sampling (the trials of a benchmark use seed, seed+1, ...)")
+	flag.Parse()
+
+	if *sweep != "" {
+		sweepEngines, err := readEngines(*sweep)
+		if err != nil {
+			log.Fatalln("😡", err)
+		}
+		engines = append(engines, sweepEngines...)
+	}
+
+	// Default engines: the models of the .env file
+	if len(engines) == 0 {
+		if model := os.Getenv("MODEL_RUNNER_LLM"); model != "" {
+			engines = append(engines, "dmr="+model)
+		}
+		if model := os.Getenv("OLLAMA_LLM"); model != "" {
+			engines = append(engines, "ollama="+model)
+		}
+	}
+	if len(engines) == 0 {
+		log.Fatalln("😡 no engine: use -engine provider=model or set MODEL_RUNNER_LLM / OLLAMA_LLM")
+	}
+
+	// Default scenarios: all the scenario files of the directory
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	scenarios, err := scenario.LoadAll(paths...)
+	if err != nil {
+		log.Fatalln("😡", err)
+	}
+
+	if *trials > 1 || *sweep != "" {
+		if *temperature < 0 {
+			*temperature = benchmarkTemperature
+		}
+		benchmark(ctx, engines, scenarios, *trials, *seed, *temperature, *markdown, *sweep != "")
+		return
+	}
+
+	options := []engine.CompletionOption{engine.WithSeed(*seed)}
+	if *temperature >= 0 {
+		options = append(options, engine.WithTemperature(*temperature))
+	}
+
+	failed := 0
+	for _, spec := range engines {
+		toolEngine, err := newEngine(ctx, spec)
+		if err != nil {
+			log.Fatalln("😡", err)
+		}
+		for _, testCase := range scenarios {
+			result, err := scenario.Run(toolEngine, testCase, options...)
+			if err != nil {
+				fmt.Println("😡", err)
+				failed++
+				continue
+			}
+			fmt.Println(result)
+			if !result.Passed() {
+				failed++
+			}
+		}
+	}
+
+	fmt.Printf("\n🎯 %d runs, %d failed\n", len(engines)*len(scenarios), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// benchmarkTemperature is the default temperature of a benchmark:
+// with a temperature of 0, the trials would be the same deterministic request
+const benchmarkTemperature = 0.7
+
+// benchmark runs each scenario several times with each engine (a seed per trial),
+// and writes the Markdown table of the statistics (and the ranking of the engines for a sweep)
+func benchmark(ctx context.Context, engines []string, scenarios []scenario.Scenario, trials int, seed int64, temperature float64, markdown string, rank bool) {
+	var benchmarks []scenario.Benchmark
+	for _, spec := range engines {
+		toolEngine, err := newEngine(ctx, spec)
+		if err != nil {
+			log.Fatalln("😡", err)
+		}
+		for _, testCase := range scenarios {
+			fmt.Printf("⏳ %s (%s): %d trials\n", testCase.Name, toolEngine, trials)
+			result := scenario.RunBenchmark(toolEngine, testCase, trials, seed, engine.WithTemperature(temperature))
+			fmt.Println(result)
+			if result.Err != nil {
+				fmt.Println("😡", result.Err)
+			}
+			benchmarks = append(benchmarks, result)
+		}
+	}
+
+	table := scenario.MarkdownTable(benchmarks)
+	if rank {
+		rankings := scenario.Rank(benchmarks)
+		fmt.Println()
+		for _, ranking := range rankings {
+			fmt.Println(ranking)
+		}
+		table += "\n" + scenario.RankingTable(rankings)
+	}
+	if markdown == "" {
+		fmt.Println()
+		fmt.Print(table)
+		return
+	}
+	if err := os.WriteFile(markdown, []byte(table), 0o644); err != nil {
+		log.Fatalln("😡", err)
+	}
+	fmt.Println("📝", markdown)
+}